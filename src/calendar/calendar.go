@@ -2,62 +2,84 @@ package calendar
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/sourcegraph/conc"
-	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
 
 	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/push"
+	"github.com/aleksasiriski/smerac-go/src/source"
+	"github.com/aleksasiriski/smerac-go/src/store"
 	"github.com/aleksasiriski/smerac-go/src/webhook"
 )
 
-func (week *Week) Generate(items []*calendar.Event) {
+// Split breaks the event into one occurrence for every day it spans inside of the window
+func (event Event) Split(windowStart time.Time, windowEnd time.Time) []Event {
+	occurrences := make([]Event, 0, 1)
+
+	if !event.End.After(event.Start) {
+		return append(occurrences, event)
+	}
+
+	location := event.Start.Location()
+	dayStart := time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, location)
+	for ; dayStart.Before(event.End); dayStart = dayStart.AddDate(0, 0, 1) {
+		dayEnd := dayStart.AddDate(0, 0, 1)
+		if !dayEnd.After(windowStart) || !dayStart.Before(windowEnd) {
+			continue
+		}
+
+		occurrence := event
+		if occurrence.Start.Before(dayStart) {
+			occurrence.Start = dayStart
+		}
+		if occurrence.End.After(dayEnd) {
+			occurrence.End = dayEnd
+		}
+		occurrence.AllDay = event.AllDay || (occurrence.Start.Equal(dayStart) && occurrence.End.Equal(dayEnd))
+
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return occurrences
+}
+
+func (week *Week) Day(weekday time.Weekday) *Weekday {
+	switch weekday {
+	case time.Monday:
+		return &week.Mon
+	case time.Tuesday:
+		return &week.Tue
+	case time.Wednesday:
+		return &week.Wed
+	case time.Thursday:
+		return &week.Thu
+	case time.Friday:
+		return &week.Fri
+	case time.Saturday:
+		return &week.Sat
+	default:
+		return &week.Sun
+	}
+}
+
+func (week *Week) Generate(items []Event) {
 	foundItems := false
 
 	for _, item := range items {
 		log.Trace().
 			Str("item", fmt.Sprintf("%v", item)).
 			Msg("Appending")
-		weekday, err := time.Parse(time.RFC3339, item.Start.DateTime)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Msg("Failed parsing time")
-		} else {
-			switch weekday.Weekday().String() {
-			case "Monday":
-				{
-					week.Mon.Items = append(week.Mon.Items, item)
-				}
-			case "Tuesday":
-				{
-					week.Tue.Items = append(week.Tue.Items, item)
-				}
-			case "Wednesday":
-				{
-					week.Wed.Items = append(week.Wed.Items, item)
-				}
-			case "Thursday":
-				{
-					week.Thu.Items = append(week.Thu.Items, item)
-				}
-			case "Friday":
-				{
-					week.Fri.Items = append(week.Fri.Items, item)
-				}
-			case "Saturday":
-				{
-					week.Sat.Items = append(week.Sat.Items, item)
-				}
-			case "Sunday":
-				{
-					week.Sun.Items = append(week.Sun.Items, item)
-				}
-			}
+
+		for _, occurrence := range item.Split(week.Start, week.End) {
+			day := week.Day(occurrence.Start.Weekday())
+			day.Items = append(day.Items, occurrence)
 			foundItems = true
 		}
 	}
@@ -70,34 +92,25 @@ func (week *Week) Generate(items []*calendar.Event) {
 
 func (day Weekday) Parse() WeekdayParsed {
 	dayParsed := WeekdayParsed{
-		Name:  day.Name,
-		Items: make([]ItemParsed, 0),
+		Name:       day.Name,
+		AllDayName: day.AllDayName,
+		Items:      make([]ItemParsed, 0),
 	}
 
 	for _, item := range day.Items {
-		nameinfo := strings.SplitN(item.Summary, ",", 2)
-		name := nameinfo[0]
-		info := nameinfo[1]
+		name := item.Name
+		info := item.Info
 
-		start, err := time.Parse(time.RFC3339, item.Start.DateTime)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Msg("Failed parsing time")
-		}
-		end, err := time.Parse(time.RFC3339, item.End.DateTime)
-		if err != nil {
-			log.Error().
-				Err(err).
-				Msg("Failed parsing time")
-		}
+		start := item.Start
+		end := item.End
+		allDay := item.AllDay
 
 		foundEvent := false
 		for itemIndex, itemParsed := range dayParsed.Items {
 			compName := strings.ReplaceAll(strings.ToUpper(name), " ", "")
 			compNameParsed := strings.ReplaceAll(strings.ToUpper(itemParsed.Name), " ", "")
 
-			if compName == compNameParsed {
+			if compName == compNameParsed && item.Label == itemParsed.Label {
 				foundInfo := false
 				for infoIndex, infoParsed := range itemParsed.Infos {
 					compInfo := strings.ReplaceAll(strings.ToUpper(info), " ", "")
@@ -112,6 +125,7 @@ func (day Weekday) Parse() WeekdayParsed {
 							Msg("Same item and info names")
 						dayParsed.Items[itemIndex].Infos[infoIndex].Start = append(infoParsed.Start, start)
 						dayParsed.Items[itemIndex].Infos[infoIndex].End = append(infoParsed.End, end)
+						dayParsed.Items[itemIndex].Infos[infoIndex].AllDay = append(infoParsed.AllDay, allDay)
 
 						foundInfo = true
 						break
@@ -124,12 +138,14 @@ func (day Weekday) Parse() WeekdayParsed {
 						Str("compNameParsed", compNameParsed).
 						Msg("Same item names, but no info")
 					newInfo := Info{
-						Name:  info,
-						Start: make([]time.Time, 1),
-						End:   make([]time.Time, 1),
+						Name:   info,
+						Start:  make([]time.Time, 1),
+						End:    make([]time.Time, 1),
+						AllDay: make([]bool, 1),
 					}
 					newInfo.Start[0] = start
 					newInfo.End[0] = end
+					newInfo.AllDay[0] = allDay
 
 					dayParsed.Items[itemIndex].Infos = append(itemParsed.Infos, newInfo)
 				}
@@ -141,16 +157,20 @@ func (day Weekday) Parse() WeekdayParsed {
 
 		if !foundEvent {
 			newInfo := Info{
-				Name:  info,
-				Start: make([]time.Time, 1),
-				End:   make([]time.Time, 1),
+				Name:   info,
+				Start:  make([]time.Time, 1),
+				End:    make([]time.Time, 1),
+				AllDay: make([]bool, 1),
 			}
 			newInfo.Start[0] = start
 			newInfo.End[0] = end
+			newInfo.AllDay[0] = allDay
 
 			newItemParsed := ItemParsed{
-				Name:  name,
-				Infos: make([]Info, 1),
+				Name:    name,
+				Label:   item.Label,
+				ColorId: item.ColorId,
+				Infos:   make([]Info, 1),
 			}
 			newItemParsed.Infos[0] = newInfo
 
@@ -195,86 +215,90 @@ func (week *Week) Parse() WeekParsed {
 	return weekParsed
 }
 
-func (day WeekdayParsed) Stringify() string {
+func (day WeekdayParsed) Stringify(tmpl *template.Template, week WeekParsed) (string, error) {
 	if len(day.Items) == 0 {
-		return ""
+		return "", nil
 	}
 
-	spacer := "-------------------------"
-	output := spacer + "\n\n**" + day.Name + ":**\n\n"
-
-	for _, item := range day.Items {
-		output += "--- **" + item.Name + "** ---\n"
-
-		for _, info := range item.Infos {
-			output += info.Name + "\n"
-
-			for index := range info.Start {
-				output += "**" + info.Start[index].Format("15:04") + "** - " + info.End[index].Format("15:04") + "\n"
-			}
-		}
+	output := new(strings.Builder)
+	if err := tmpl.Execute(output, DayTemplate{
+		WeekdayParsed: day,
+		Week:          week,
+	}); err != nil {
+		return "", err
+	}
 
-		output += "\n"
+	if strings.TrimSpace(output.String()) == "" {
+		return "", nil
 	}
-	output += spacer
 
-	return output
+	return output.String(), nil
 }
 
-func (week WeekParsed) Stringify() WeekOutput {
+func (week WeekParsed) Stringify(tmpl *template.Template, limit int) (WeekOutput, error) {
 	weekOutput := WeekOutput{}
+	errs := make([]error, 7)
 	var worker conc.WaitGroup
 
 	worker.Go(func() {
-		weekOutput.Mon = week.Mon.Stringify()
+		weekOutput.Mon, errs[0] = week.Mon.Split(tmpl, week, limit)
 	})
 	worker.Go(func() {
-		weekOutput.Tue = week.Tue.Stringify()
+		weekOutput.Tue, errs[1] = week.Tue.Split(tmpl, week, limit)
 	})
 	worker.Go(func() {
-		weekOutput.Wed = week.Wed.Stringify()
+		weekOutput.Wed, errs[2] = week.Wed.Split(tmpl, week, limit)
 	})
 	worker.Go(func() {
-		weekOutput.Thu = week.Thu.Stringify()
+		weekOutput.Thu, errs[3] = week.Thu.Split(tmpl, week, limit)
 	})
 	worker.Go(func() {
-		weekOutput.Fri = week.Fri.Stringify()
+		weekOutput.Fri, errs[4] = week.Fri.Split(tmpl, week, limit)
 	})
 	worker.Go(func() {
-		weekOutput.Sat = week.Sat.Stringify()
+		weekOutput.Sat, errs[5] = week.Sat.Split(tmpl, week, limit)
 	})
 	worker.Go(func() {
-		weekOutput.Sun = week.Sun.Stringify()
+		weekOutput.Sun, errs[6] = week.Sun.Split(tmpl, week, limit)
 	})
 
 	log.Trace().
 		Msg("Waiting for stringification")
 	worker.Wait()
-	return weekOutput
+	return weekOutput, errors.Join(errs...)
 }
 
-func generateAndParseWeek(items []*calendar.Event, namedDays config.NamedDays) WeekParsed {
+func generateAndParseWeek(items []Event, namedDays config.NamedDays, start time.Time, end time.Time) WeekParsed {
 	week := Week{
+		Start: start,
+		End:   end,
 		Mon: Weekday{
-			Name: namedDays.Monday,
+			Name:       namedDays.Monday,
+			AllDayName: namedDays.AllDay,
 		},
 		Tue: Weekday{
-			Name: namedDays.Tuesday,
+			Name:       namedDays.Tuesday,
+			AllDayName: namedDays.AllDay,
 		},
 		Wed: Weekday{
-			Name: namedDays.Wednesday,
+			Name:       namedDays.Wednesday,
+			AllDayName: namedDays.AllDay,
 		},
 		Thu: Weekday{
-			Name: namedDays.Thursday,
+			Name:       namedDays.Thursday,
+			AllDayName: namedDays.AllDay,
 		},
 		Fri: Weekday{
-			Name: namedDays.Friday,
+			Name:       namedDays.Friday,
+			AllDayName: namedDays.AllDay,
 		},
 		Sat: Weekday{
-			Name: namedDays.Saturday,
+			Name:       namedDays.Saturday,
+			AllDayName: namedDays.AllDay,
 		},
 		Sun: Weekday{
-			Name: namedDays.Sunday,
+			Name:       namedDays.Sunday,
+			AllDayName: namedDays.AllDay,
 		},
 	}
 
@@ -287,199 +311,301 @@ func generateAndParseWeek(items []*calendar.Event, namedDays config.NamedDays) W
 	return weekParsed
 }
 
-func updateCalendar(calendarId string, namedDays config.NamedDays, google config.Google) (WeekParsed, error) {
-	week := WeekParsed{}
-
-	log.Trace().
-		Msg("Getting calendar via API")
+// check is the week of a previous update, along with the day its window started on
+type check struct {
+	week WeekParsed
+	day  string
+}
 
-	ctx := context.Background()
-	calendarService, err := calendar.NewService(ctx, option.WithAPIKey(google.Token))
-	if err != nil {
-		return week, err
-	}
+// updateCalendar merges the events of all origins, tagging them with their labels when asked to.
+// When every origin can tell what changed and none of it is inside of the window, the previous check is returned unchanged
+func updateCalendar(ctx context.Context, origins []Origin, tag bool, namedDays config.NamedDays, previous *check) (*check, bool, error) {
+	currentTime := time.Now()
+	weekTime := currentTime.AddDate(0, 0, 7)
+	firstDay := currentTime.Format(time.DateOnly)
+	lastDay := weekTime.Format(time.DateOnly)
 
-	currentTime := time.Now().Format(time.RFC3339)
-	weekTime := time.Now().AddDate(0, 0, 7).Format(time.RFC3339)
+	// a new day moves the window, so it always needs a new week
+	changed := previous == nil || previous.day != firstDay
 
-	if calendar, err := calendarService.Events.List(calendarId).ShowDeleted(false).
-		SingleEvents(true).TimeMin(currentTime).TimeMax(weekTime).OrderBy("startTime").Do(); err != nil {
-		return week, err
-	} else {
-		log.Debug().Msg("Decoded API response")
-		week = generateAndParseWeek(calendar.Items, namedDays)
-	}
+	items := make([]Event, 0)
+	for index, origin := range origins {
+		events, err := origin.Source.Events(ctx, currentTime, weekTime)
+		if err != nil {
+			switch {
+			case origin.Label != "":
+				return nil, false, fmt.Errorf("source %s: %w", origin.Label, err)
+			case len(origins) > 1:
+				return nil, false, fmt.Errorf("source %d: %w", index+1, err)
+			}
+			return nil, false, err
+		}
 
-	return week, nil
-}
+		if changer, ok := origin.Source.(source.Changer); !ok || changer.Changed() == nil {
+			changed = true
+		} else {
+			for _, day := range changer.Changed() {
+				if day >= firstDay && day <= lastDay {
+					changed = true
+				}
+			}
+		}
 
-func outputDay(day string, webhookUrl string) error {
-	if day != "" {
-		if err := webhook.SendMessage(webhookUrl, webhook.Message{
-			Content: day,
-		}); err != nil {
-			return err
+		for _, event := range events {
+			item := Event{
+				Event: event,
+			}
+			item.Name, item.Info = origin.Rule.Apply(item)
+			if tag {
+				item.Label = origin.Label
+			}
+			items = append(items, item)
 		}
 	}
-	return nil
-}
 
-func outputWeek(channelId string, week WeekOutput) error {
-	// messageObjects, err := ChannelMessages(channelId, 100, "", "", "")
-	// if err != nil {
-	// 	return err
-	// }
+	if !changed {
+		return previous, false, nil
+	}
 
-	// messageIds := make([]string, 0)
-	// for _, messageObject := range messageObjects {
-	// 	messageIds = append(messageIds, messageObject.ID)
-	// }
+	// every source is sorted on its own, merged ones have to be sorted again
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Start.Before(items[j].Start)
+	})
 
-	// err = ChannelMessagesBulkDelete(channelId, messageIds)
-	// if err != nil {
-	// 	return err
-	// }
+	return &check{
+		week: generateAndParseWeek(items, namedDays, currentTime, weekTime),
+		day:  firstDay,
+	}, true, nil
+}
 
-	if err := outputDay(week.Mon, channelId); err != nil {
-		return err
-	}
-	if err := outputDay(week.Tue, channelId); err != nil {
-		return err
+// updateDestination returns false when something failed, so the destination is tried again on the next check
+func updateDestination(ctx context.Context, st store.Store, name string, index int, destination *Destination, stateKey string, week WeekParsed) bool {
+	state, err := getState(st, stateKey)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Int("destination", index).
+			Msg(fmt.Sprintf("Failed while getting old calendar %s:", name))
 	}
-	if err := outputDay(week.Wed, channelId); err != nil {
-		return err
+
+	now := time.Now()
+	if destination.schedule != nil && !destination.due(now, state.UpdatedAt) {
+		log.Debug().
+			Str("name", name).
+			Int("destination", index).
+			Time("next", destination.Next()).
+			Msg("Calendar isn't due yet")
+		return true
 	}
-	if err := outputDay(week.Thu, channelId); err != nil {
-		return err
+
+	posts, err := destination.Posts(week)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Int("destination", index).
+			Msg(fmt.Sprintf("Failed while rendering calendar %s:", name))
+		return false
 	}
-	if err := outputDay(week.Fri, channelId); err != nil {
-		return err
+
+	log.Debug().
+		Str("new", fmt.Sprintf("%v", postsOutput(posts))).
+		Str("old", fmt.Sprintf("%v", state.Output)).
+		Msg("Comparing calendars")
+	if destination.schedule != nil {
+		// every scheduled send is a new message, even if nothing changed
+		state.Messages = make(map[string][]string)
+		state.Output = make(map[string]string)
+	} else if state.Hash == outputHash(postsOutput(posts)) {
+		log.Debug().
+			Str("name", name).
+			Int("destination", index).
+			Msg("Calendar is the same")
+		return true
 	}
-	if err := outputDay(week.Sat, channelId); err != nil {
-		return err
+
+	log.Trace().
+		Str("name", name).
+		Int("destination", index).
+		Msg("Outputting calendar")
+
+	err = outputPosts(ctx, destination.Notifier, destination.Cleanup, posts, &state)
+	settled := err == nil
+	if errors.Is(err, webhook.ErrUnknownWebhook) || errors.Is(err, webhook.ErrUnauthorized) {
+		log.Error().
+			Err(err).
+			Int("destination", index).
+			Msg(fmt.Sprintf("Destination of calendar %s was deleted or its credentials are wrong:", name))
+	} else if err != nil {
+		log.Error().
+			Err(err).
+			Int("destination", index).
+			Msg(fmt.Sprintf("Failed while outputting calendar %s:", name))
+	} else if destination.schedule != nil {
+		destination.sent(now)
 	}
-	if err := outputDay(week.Sun, channelId); err != nil {
-		return err
+	if err := saveState(st, stateKey, state); err != nil {
+		log.Error().
+			Err(err).
+			Int("destination", index).
+			Msg(fmt.Sprintf("Failed while saving calendar %s:", name))
+		settled = false
 	}
 
-	return nil
+	return settled
 }
 
-func getOldWeekOutput(webhookUrl string, namedDays config.NamedDays) (WeekOutput, error) {
-	weekOutput := WeekOutput{}
-
-	// messageObjects, err := discord.ChannelMessages(channelId, 100, "", "", "")
-	// if err != nil {
-	// 	return weekOutput, err
-	// }
-
-	// for _, messageObject := range messageObjects {
-	// 	if strings.Contains(messageObject.Content, namedDays.Monday) {
-	// 		weekOutput.Mon = messageObject.Content
-	// 	}
-	// 	if strings.Contains(messageObject.Content, namedDays.Tuesday) {
-	// 		weekOutput.Tue = messageObject.Content
-	// 	}
-	// 	if strings.Contains(messageObject.Content, namedDays.Wednesday) {
-	// 		weekOutput.Wed = messageObject.Content
-	// 	}
-	// 	if strings.Contains(messageObject.Content, namedDays.Thursday) {
-	// 		weekOutput.Thu = messageObject.Content
-	// 	}
-	// 	if strings.Contains(messageObject.Content, namedDays.Friday) {
-	// 		weekOutput.Fri = messageObject.Content
-	// 	}
-	// 	if strings.Contains(messageObject.Content, namedDays.Saturday) {
-	// 		weekOutput.Sat = messageObject.Content
-	// 	}
-	// 	if strings.Contains(messageObject.Content, namedDays.Sunday) {
-	// 		weekOutput.Sun = messageObject.Content
-	// 	}
-	// }
-
-	return weekOutput, nil
-}
+// wait returns false if the context was cancelled before the duration passed, and returns early when the trigger fires
+func wait(ctx context.Context, duration time.Duration, trigger <-chan struct{}) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
 
-func sameWeeks(weekA WeekOutput, weekB WeekOutput) bool {
-	if weekA.Mon != weekB.Mon {
-		return false
-	}
-	if weekA.Tue != weekB.Tue {
-		return false
-	}
-	if weekA.Wed != weekB.Wed {
-		return false
-	}
-	if weekA.Thu != weekB.Thu {
-		return false
-	}
-	if weekA.Fri != weekB.Fri {
-		return false
-	}
-	if weekA.Sat != weekB.Sat {
-		return false
-	}
-	if weekA.Sun != weekB.Sun {
+	select {
+	case <-ctx.Done():
 		return false
+	case <-timer.C:
+		return true
+	case <-trigger:
+		return true
 	}
-	return true
 }
 
-func Update(ctx context.Context, conf *config.Config) {
+func Update(ctx context.Context, conf *config.Config, st store.Store) {
 	var worker conc.WaitGroup
+
+	receiver, err := push.New(conf.Google.Push)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Invalid push configuration, only polling calendars")
+	}
+	if receiver != nil {
+		worker.Go(func() {
+			if err := receiver.Listen(ctx); err != nil {
+				log.Error().
+					Err(err).
+					Msg("Failed listening for notifications")
+			}
+		})
+	}
+
 	for _, calendarIterator := range conf.Calendars {
 		calendarObject := calendarIterator
 		worker.Go(func() {
-			for {
-				log.Debug().
-					Str("name", calendarObject.Name).
-					Msg("Updating calendar")
+			destinations := make([]*Destination, 0, len(calendarObject.Destinations))
+			for _, destinationConf := range calendarObject.Destinations {
+				destination, err := NewDestination(destinationConf, calendarObject.Name)
+				if err != nil {
+					log.Error().
+						Err(err).
+						Msg(fmt.Sprintf("Invalid destination for calendar %s:", calendarObject.Name))
+					return
+				}
+				destinations = append(destinations, destination)
+			}
+			schedule, err := NewSchedule(calendarObject.Schedule, calendarObject.Timezone, calendarObject.Jitter, calendarObject.TimeBetweenChecks)
+			if err != nil {
+				log.Error().
+					Err(err).
+					Msg(fmt.Sprintf("Invalid schedule for calendar %s:", calendarObject.Name))
+				return
+			}
 
-				week, err := updateCalendar(calendarObject.Id, conf.Days, conf.Google)
+			origins := make([]Origin, 0, len(calendarObject.Sources))
+			sourceIds := make([]string, 0, len(calendarObject.Sources))
+			for _, sourceConf := range calendarObject.Sources {
+				rule, err := NewRule(sourceConf.Parse)
 				if err != nil {
 					log.Error().
 						Err(err).
-						Msg(fmt.Sprintf("Failed while updating calendar %s:", calendarObject.Name))
+						Msg(fmt.Sprintf("Invalid parse rule for calendar %s:", calendarObject.Name))
+					return
 				}
-				weekOutput := week.Stringify()
-				weekOutputOld, err := getOldWeekOutput(calendarObject.Webhook, conf.Days)
+
+				src, err := source.New(sourceConf, conf.Google, st)
 				if err != nil {
 					log.Error().
 						Err(err).
-						Msg(fmt.Sprintf("Failed while getting old calendar %s:", calendarObject.Name))
+						Msg(fmt.Sprintf("Invalid source for calendar %s:", calendarObject.Name))
+					return
+				}
+
+				// sources without a label stay untagged, their ids and urls can hold secrets
+				origins = append(origins, Origin{
+					Source: src,
+					Rule:   rule,
+					Label:  sourceConf.Label,
+				})
+				sourceIds = append(sourceIds, source.Id(sourceConf))
+			}
+
+			var trigger chan struct{}
+			if receiver != nil {
+				for _, origin := range origins {
+					watcher, ok := origin.Source.(source.Watcher)
+					if !ok {
+						continue
+					}
+					if trigger == nil {
+						trigger = make(chan struct{}, 1)
+					}
+					worker.Go(func() {
+						receiver.Watch(ctx, calendarObject.Name, watcher, trigger)
+					})
 				}
+			}
 
+			var previous *check
+			settled := make([]bool, len(destinations))
+			for {
 				log.Debug().
-					Str("new", fmt.Sprintf("%v", weekOutput)).
-					Str("old", fmt.Sprintf("%v", weekOutputOld)).
-					Msg("Comparing calendars")
-				if sameWeeks(weekOutput, weekOutputOld) {
-					log.Debug().
-						Str("name", calendarObject.Name).
-						Msg("Calendar is the same")
-				} else {
-					log.Trace().
-						Str("name", calendarObject.Name).
-						Msg("Outputting calendar")
+					Str("name", calendarObject.Name).
+					Msg("Updating calendar")
 
-					if err := outputWeek(calendarObject.Webhook, weekOutput); err != nil {
-						log.Error().
-							Err(err).
-							Msg(fmt.Sprintf("Failed while outputting calendar %s:", calendarObject.Name))
+				// the calendar is fetched and parsed once for all of the destinations
+				if current, changed, err := updateCalendar(ctx, origins, calendarObject.Tag, conf.Days, previous); err != nil {
+					log.Error().
+						Err(err).
+						Msg(fmt.Sprintf("Failed while updating calendar %s:", calendarObject.Name))
+				} else {
+					if !changed {
+						log.Debug().
+							Str("name", calendarObject.Name).
+							Msg("Calendar has no changes")
+					}
+					previous = current
+
+					for index, destination := range destinations {
+						// without changes only failed and scheduled destinations have anything left to do
+						if !changed && settled[index] && destination.schedule == nil {
+							continue
+						}
+						stateKey := store.Key(append(sourceIds, destination.Id)...)
+						settled[index] = updateDestination(ctx, st, calendarObject.Name, index, destination, stateKey, current.week)
 					}
 				}
 
+				next := schedule.Next(time.Now())
+				for _, destination := range destinations {
+					if sendAt := destination.Next(); !sendAt.IsZero() && sendAt.Before(next) {
+						next = sendAt
+					}
+				}
 				log.Trace().
 					Str("name", calendarObject.Name).
+					Time("next", next).
 					Msg("Sleeping calendar")
 
-				if calendarObject.TimeBetweenChecks == 0 {
-					time.Sleep(time.Hour * 3)
-				} else {
-					time.Sleep(time.Hour * time.Duration(calendarObject.TimeBetweenChecks))
+				if !wait(ctx, time.Until(next), trigger) {
+					log.Debug().
+						Str("name", calendarObject.Name).
+						Msg("Stopping calendar")
+					return
 				}
 			}
 		})
 	}
-	<-ctx.Done()
+
+	log.Trace().
+		Msg("Waiting for calendars to stop")
+	worker.Wait()
 }
@@ -0,0 +1,156 @@
+package calendar
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/source"
+)
+
+const utcFeed = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Google Inc//Google Calendar 70.9054//EN\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:late@test\r\n" +
+	"DTSTAMP:20240101T000000Z\r\n" +
+	"DTSTART:20240108T233000Z\r\n" +
+	"DTEND:20240109T003000Z\r\n" +
+	"SUMMARY:Late\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestUtcFeedRendersInLocalTime(t *testing.T) {
+	belgrade, err := time.LoadLocation("Europe/Belgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := time.Local
+	time.Local = belgrade
+	t.Cleanup(func() {
+		time.Local = local
+	})
+
+	file := filepath.Join(t.TempDir(), "feed.ics")
+	if err := os.WriteFile(file, []byte(utcFeed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	src, err := source.New(config.Source{Type: "ics", File: file}, config.Google{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 8, 0, 0, 0, 0, belgrade)
+	end := start.AddDate(0, 0, 7)
+	events, err := src.Events(context.Background(), start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule, err := NewRule(config.ParseRule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := make([]Event, 0, len(events))
+	for _, event := range events {
+		item := Event{
+			Event: event,
+		}
+		item.Name, item.Info = rule.Apply(item)
+		items = append(items, item)
+	}
+
+	week := generateAndParseWeek(items, config.NamedDays{}, start, end)
+	if len(week.Mon.Items) != 0 {
+		t.Errorf("Monday has %+v, the event is on Tuesday in Belgrade", week.Mon.Items)
+	}
+	if len(week.Tue.Items) != 1 {
+		t.Fatalf("Tuesday has %d items, want 1", len(week.Tue.Items))
+	}
+	if lines := week.Tue.Items[0].Infos[0].Lines(""); lines != "**00:30** - 01:30" {
+		t.Errorf("rendered %q, want %q", lines, "**00:30** - 01:30")
+	}
+}
+
+// changerSource is a source that reports fixed changes, nil meaning any day
+type changerSource struct {
+	changed []string
+}
+
+func (changer changerSource) Events(ctx context.Context, start time.Time, end time.Time) ([]source.Event, error) {
+	return []source.Event{{
+		Summary: "Math, Room 5",
+		Start:   start.Add(time.Hour),
+		End:     start.Add(2 * time.Hour),
+	}}, nil
+}
+
+func (changer changerSource) Changed() []string {
+	return changer.changed
+}
+
+// plainSource can't tell what changed
+type plainSource struct{}
+
+func (plain plainSource) Events(ctx context.Context, start time.Time, end time.Time) ([]source.Event, error) {
+	return []source.Event{}, nil
+}
+
+func TestUpdateCalendarChanges(t *testing.T) {
+	today := time.Now().Format(time.DateOnly)
+	inWindow := time.Now().AddDate(0, 0, 3).Format(time.DateOnly)
+	afterWindow := time.Now().AddDate(0, 0, 20).Format(time.DateOnly)
+	yesterday := time.Now().AddDate(0, 0, -1).Format(time.DateOnly)
+
+	rule, err := NewRule(config.ParseRule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		sources  []source.Source
+		previous string
+		changed  bool
+	}{
+		{"first check", []source.Source{changerSource{[]string{}}}, "", true},
+		{"no changes", []source.Source{changerSource{[]string{}}}, today, false},
+		{"changes after the window", []source.Source{changerSource{[]string{afterWindow}}}, today, false},
+		{"changes before the window", []source.Source{changerSource{[]string{yesterday}}}, today, false},
+		{"changes in the window", []source.Source{changerSource{[]string{afterWindow, inWindow}}}, today, true},
+		{"full sync", []source.Source{changerSource{nil}}, today, true},
+		{"new day", []source.Source{changerSource{[]string{}}}, yesterday, true},
+		{"source without changes", []source.Source{changerSource{[]string{}}, plainSource{}}, today, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			origins := make([]Origin, 0, len(test.sources))
+			for _, src := range test.sources {
+				origins = append(origins, Origin{Source: src, Rule: rule})
+			}
+
+			var previous *check
+			if test.previous != "" {
+				previous = &check{day: test.previous}
+			}
+
+			current, changed, err := updateCalendar(context.Background(), origins, false, config.NamedDays{}, previous)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != test.changed {
+				t.Errorf("changed = %t, want %t", changed, test.changed)
+			}
+			if !changed && current != previous {
+				t.Error("unchanged check didn't keep the previous week")
+			}
+			if changed && current.day != today {
+				t.Errorf("day = %s, want %s", current.day, today)
+			}
+		})
+	}
+}
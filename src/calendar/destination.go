@@ -0,0 +1,316 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+// Destination is where a calendar is posted, with its own look
+type Destination struct {
+	Id       string
+	Cleanup  string
+	Notifier webhook.Notifier
+	kind     string
+	render   config.Render
+	days     config.NamedDays
+	template *template.Template
+	mentions []mention
+	name     string
+	username string
+	iconUrl  string
+	subject  *template.Template
+	schedule *Schedule
+	nextSend time.Time
+}
+
+type mention struct {
+	regex    *regexp.Regexp
+	roles    []string
+	users    []string
+	everyone bool
+}
+
+const defaultSubject = `{{ .Name }} schedule`
+
+// NewDestination takes the name of the calendar, which email subjects can use
+func NewDestination(conf config.Destination, name string) (*Destination, error) {
+	notifier, err := webhook.New(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := NewTemplate(conf.Render)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	mentions := make([]mention, 0, len(conf.Mentions))
+	for _, mentionConf := range conf.Mentions {
+		regex, err := regexp.Compile("(?i)" + mentionConf.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mention match: %w", err)
+		}
+
+		mentions = append(mentions, mention{
+			regex:    regex,
+			roles:    mentionConf.Roles,
+			users:    mentionConf.Users,
+			everyone: mentionConf.Everyone,
+		})
+	}
+
+	subject := conf.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+	subjectTmpl, err := template.New("subject").Funcs(templateFuncs).Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject: %w", err)
+	}
+
+	// scheduled destinations are sent to at set times, not whenever the calendar changes
+	var schedule *Schedule
+	if conf.Schedule != "" {
+		parsed, err := NewSchedule(conf.Schedule, conf.Timezone, "", 0)
+		if err != nil {
+			return nil, err
+		}
+		schedule = &parsed
+	}
+
+	return &Destination{
+		Id:       webhook.Id(conf),
+		Cleanup:  conf.Cleanup,
+		Notifier: notifier,
+		kind:     conf.Type,
+		render:   conf.Render,
+		days:     conf.Days,
+		template: tmpl,
+		mentions: mentions,
+		name:     name,
+		username: conf.Username,
+		iconUrl:  conf.IconUrl,
+		subject:  subjectTmpl,
+		schedule: schedule,
+	}, nil
+}
+
+// due tells if a scheduled destination should be sent to, counting from when it last was
+func (destination *Destination) due(now time.Time, last time.Time) bool {
+	if destination.nextSend.IsZero() {
+		// never sent, so wait for the first scheduled time instead of sending on startup
+		if last.IsZero() {
+			last = now
+		}
+		destination.nextSend = destination.schedule.Next(last)
+	}
+
+	return !now.Before(destination.nextSend)
+}
+
+func (destination *Destination) sent(now time.Time) {
+	destination.nextSend = destination.schedule.Next(now)
+}
+
+// Next is when a scheduled destination has to be sent to, zero when it isn't scheduled
+func (destination *Destination) Next() time.Time {
+	return destination.nextSend
+}
+
+// Named returns a copy of the week using other day names
+func (week WeekParsed) Named(days config.NamedDays) WeekParsed {
+	names := []struct {
+		day  *WeekdayParsed
+		name string
+	}{
+		{&week.Mon, days.Monday},
+		{&week.Tue, days.Tuesday},
+		{&week.Wed, days.Wednesday},
+		{&week.Thu, days.Thursday},
+		{&week.Fri, days.Friday},
+		{&week.Sat, days.Saturday},
+		{&week.Sun, days.Sunday},
+	}
+
+	for _, name := range names {
+		name.day.Name = name.name
+		name.day.AllDayName = days.AllDay
+	}
+
+	return week
+}
+
+func (week WeekParsed) Days() map[string]WeekdayParsed {
+	return map[string]WeekdayParsed{
+		"mon": week.Mon,
+		"tue": week.Tue,
+		"wed": week.Wed,
+		"thu": week.Thu,
+		"fri": week.Fri,
+		"sat": week.Sat,
+		"sun": week.Sun,
+	}
+}
+
+func (rule mention) matches(day WeekdayParsed) bool {
+	for _, item := range day.Items {
+		if rule.regex.MatchString(item.Name) || rule.regex.MatchString(item.Label) {
+			return true
+		}
+		for _, info := range item.Infos {
+			if rule.regex.MatchString(info.Name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// mentionLength is the longest mention line, which the content has to leave room for
+func (destination *Destination) mentionLength() int {
+	if len(destination.mentions) == 0 {
+		return 0
+	}
+
+	_, line := destination.mention(destination.mentions)
+	return length(line) + 1
+}
+
+// mention returns the allowed mentions and the line pinging them
+func (destination *Destination) mention(rules []mention) (webhook.AllowedMentions, string) {
+	allowed := webhook.AllowedMentions{}
+	pings := make([]string, 0)
+
+	for _, rule := range rules {
+		if rule.everyone && len(allowed.Parse) == 0 {
+			allowed.Parse = append(allowed.Parse, "everyone")
+			pings = append(pings, "@everyone")
+		}
+		for _, role := range rule.roles {
+			allowed.Roles = append(allowed.Roles, role)
+			pings = append(pings, "<@&"+role+">")
+		}
+		for _, user := range rule.users {
+			allowed.Users = append(allowed.Users, user)
+			pings = append(pings, "<@"+user+">")
+		}
+	}
+
+	return allowed, strings.Join(pings, " ")
+}
+
+// Posts renders the week the way the destination wants it
+func (destination *Destination) Posts(week WeekParsed) ([]Post, error) {
+	week = week.Named(destination.days)
+
+	switch destination.kind {
+	case "slack":
+		return week.SlackPosts(), nil
+	case "matrix":
+		return week.MatrixPosts(), nil
+	case "telegram":
+		return week.TelegramPosts(), nil
+	case "email":
+		return week.EmailPosts(destination.subject, destination.name)
+	case "json":
+		return week.JsonPosts(), nil
+	case "teams":
+		return week.TeamsPosts(), nil
+	case "mattermost":
+		return destination.mattermostPosts(week)
+	default:
+		return destination.discordPosts(week)
+	}
+}
+
+// mattermostPosts uses the same markdown as Discord, with room for much longer posts
+func (destination *Destination) mattermostPosts(week WeekParsed) ([]Post, error) {
+	weekOutput, err := week.Stringify(destination.template, webhook.MattermostText)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := weekOutput.Posts()
+	for _, post := range posts {
+		for index, message := range post.Messages {
+			post.Messages[index] = webhook.MattermostMessage{
+				Text:     message.(webhook.Message).Content,
+				Username: destination.username,
+				IconUrl:  destination.iconUrl,
+			}
+		}
+	}
+
+	return posts, nil
+}
+
+func (destination *Destination) discordPosts(week WeekParsed) ([]Post, error) {
+	limits := webhook.DiscordLimits
+	limits.Content -= destination.mentionLength()
+
+	posts, err := renderPosts(week, destination.template, destination.render, limits)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		for index, message := range post.Messages {
+			if discordMessage, ok := message.(webhook.Message); ok {
+				discordMessage.Username = destination.username
+				discordMessage.AvatarUrl = destination.iconUrl
+				post.Messages[index] = discordMessage
+			}
+		}
+	}
+	if len(destination.mentions) == 0 {
+		return posts, nil
+	}
+
+	days := week.Days()
+	for index, post := range posts {
+		// embeds post the whole week together
+		day, found := days[post.Key]
+
+		matched := make([]mention, 0)
+		for _, rule := range destination.mentions {
+			if (found && rule.matches(day)) || (!found && rule.matchesWeek(week)) {
+				matched = append(matched, rule)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		message, ok := posts[index].Messages[0].(webhook.Message)
+		if !ok {
+			continue
+		}
+
+		allowed, line := destination.mention(matched)
+		message.AllowedMentions = allowed
+		if message.Content == "" {
+			message.Content = line
+		} else {
+			message.Content = line + "\n" + message.Content
+		}
+		posts[index].Messages[0] = message
+	}
+
+	return posts, nil
+}
+
+func (rule mention) matchesWeek(week WeekParsed) bool {
+	for _, day := range week.Days() {
+		if rule.matches(day) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,209 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+func parseColor(color string) (int, error) {
+	if color == "" {
+		return 0, nil
+	}
+
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(color), "#"), "0x")
+	if hex == strings.ToLower(color) {
+		if parsed, err := strconv.ParseInt(color, 10, 32); err == nil {
+			return int(parsed), nil
+		}
+	}
+
+	parsed, err := strconv.ParseInt(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color %s", color)
+	}
+
+	return int(parsed), nil
+}
+
+// Lines renders the info in Discord markdown
+func (info Info) Lines(allDayName string) string {
+	return info.lines(allDayName, func(text string) string {
+		return "**" + text + "**"
+	}, func(text string) string {
+		return text
+	})
+}
+
+func (info Info) lines(allDayName string, bold func(string) string, escape func(string) string) string {
+	lines := make([]string, 0, len(info.Start)+1)
+	if info.Name != "" {
+		lines = append(lines, escape(info.Name))
+	}
+
+	for _, occurrence := range info.Occurrences() {
+		if occurrence.AllDay {
+			lines = append(lines, bold(escape(allDayName)))
+		} else {
+			lines = append(lines, bold(occurrence.Start.Format("15:04"))+" - "+occurrence.End.Format("15:04"))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func embedLength(embed webhook.Embed) int {
+	total := length(embed.Title) + length(embed.Description)
+	for _, field := range embed.Fields {
+		total += length(field.Name) + length(field.Value)
+	}
+	if embed.Footer != nil {
+		total += length(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		total += length(embed.Author.Name)
+	}
+
+	return total
+}
+
+// fieldValues joins the infos of an item, splitting them into several values when they don't fit into one field
+func fieldValues(infos []string, limit int) []string {
+	values := make([]string, 0, 1)
+
+	value := ""
+	for _, info := range infos {
+		switch {
+		case value == "" && length(info) <= limit:
+			value = info
+		case value != "" && length(value)+2+length(info) <= limit:
+			value += "\n\n" + info
+		default:
+			if value != "" {
+				values = append(values, value)
+				value = ""
+			}
+			if length(info) <= limit {
+				value = info
+			} else {
+				values = append(values, splitLines(info, limit)...)
+			}
+		}
+	}
+	if value != "" {
+		values = append(values, value)
+	}
+
+	return values
+}
+
+// Embeds renders the day as one embed, or as more of them if it has too many fields or characters
+func (day WeekdayParsed) Embeds(conf config.Embed, limits webhook.Limits) ([]webhook.Embed, error) {
+	color, err := parseColor(conf.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	// the first item with a configured event color decides the color of the day
+	for _, item := range day.Items {
+		if eventColor, found := conf.EventColors[item.ColorId]; found && item.ColorId != "" {
+			if color, err = parseColor(eventColor); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	newEmbed := func() webhook.Embed {
+		return webhook.Embed{
+			Title:  truncate(limits.EmbedTitle, day.Name),
+			Color:  color,
+			Fields: make([]webhook.Field, 0, len(day.Items)),
+		}
+	}
+
+	// leave room for the footer, which is added once the embeds are batched
+	maxLength := limits.EmbedTotal - limits.EmbedTitle
+	if limits.EmbedDescription < maxLength {
+		maxLength = limits.EmbedDescription
+	}
+
+	embeds := make([]webhook.Embed, 0, 1)
+	embed := newEmbed()
+	for _, item := range day.Items {
+		infos := make([]string, 0, len(item.Infos))
+		for _, info := range item.Infos {
+			infos = append(infos, info.Lines(day.AllDayName))
+		}
+
+		for _, value := range fieldValues(infos, limits.FieldValue) {
+			field := webhook.Field{
+				Name:  truncate(limits.FieldName, item.Title()),
+				Value: value,
+			}
+
+			if len(embed.Fields) == limits.Fields || embedLength(embed)+length(field.Name)+length(field.Value) > maxLength {
+				embeds = append(embeds, embed)
+				embed = newEmbed()
+			}
+			embed.Fields = append(embed.Fields, field)
+		}
+	}
+	if len(embed.Fields) > 0 {
+		embeds = append(embeds, embed)
+	}
+
+	return embeds, nil
+}
+
+// Embeds renders every day with events as embeds and batches them into as few messages as the limits allow
+func (week WeekParsed) Embeds(conf config.Embed, limits webhook.Limits) ([]Post, error) {
+	footer := conf.Footer
+	if footer == "" {
+		footer = "Last updated"
+	}
+	footer = truncate(limits.EmbedTitle, footer)
+
+	messages := make([]webhook.Message, 0, 1)
+
+	messageLength := 0
+	for _, day := range []WeekdayParsed{week.Mon, week.Tue, week.Wed, week.Thu, week.Fri, week.Sat, week.Sun} {
+		embeds, err := day.Embeds(conf, limits)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, embed := range embeds {
+			embedSize := embedLength(embed)
+
+			last := len(messages) - 1
+			if last == -1 || len(messages[last].Embeds) == limits.Embeds || messageLength+embedSize+length(footer) > limits.EmbedTotal {
+				messages = append(messages, webhook.Message{})
+				last++
+				messageLength = 0
+			}
+
+			messages[last].Embeds = append(messages[last].Embeds, embed)
+			messageLength += embedSize
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	for index := range messages {
+		embeds := messages[index].Embeds
+		embeds[len(embeds)-1].Footer = &webhook.Footer{
+			Text: footer,
+		}
+	}
+
+	return []Post{{
+		Key:      "week",
+		Messages: anyMessages(messages),
+	}}, nil
+}
@@ -0,0 +1,293 @@
+package calendar
+
+import (
+	"html"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+func htmlBold(text string) string {
+	return "<b>" + text + "</b>"
+}
+
+func plain(text string) string {
+	return text
+}
+
+// Text renders the day without any markup
+func (day WeekdayParsed) Text() string {
+	if len(day.Items) == 0 {
+		return ""
+	}
+
+	blocks := make([]string, 0, len(day.Items)+1)
+	blocks = append(blocks, day.Name)
+	for _, item := range day.Items {
+		lines := []string{item.Title()}
+		for _, info := range item.Infos {
+			lines = append(lines, info.lines(day.AllDayName, plain, plain))
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// Html renders the day with the basic tags chat clients and email agree on
+func (day WeekdayParsed) Html() string {
+	if len(day.Items) == 0 {
+		return ""
+	}
+
+	blocks := make([]string, 0, len(day.Items)+1)
+	blocks = append(blocks, htmlBold(html.EscapeString(day.Name)))
+	for _, item := range day.Items {
+		lines := []string{htmlBold(html.EscapeString(item.Title()))}
+		for _, info := range item.Infos {
+			lines = append(lines, info.lines(day.AllDayName, htmlBold, html.EscapeString))
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// MatrixPosts makes a post for every day with events, with the HTML as the formatted body
+func (week WeekParsed) MatrixPosts() []Post {
+	posts := make([]Post, 0, len(dayKeys))
+
+	days := week.Days()
+	for _, key := range dayKeys {
+		day := days[key]
+		if len(day.Items) == 0 {
+			continue
+		}
+
+		posts = append(posts, Post{
+			Key: key,
+			Messages: []any{webhook.MatrixMessage{
+				MsgType:       "m.text",
+				Body:          day.Text(),
+				Format:        "org.matrix.custom.html",
+				FormattedBody: strings.ReplaceAll(day.Html(), "\n", "<br>"),
+			}},
+		})
+	}
+
+	return posts
+}
+
+// telegramUnit is a piece of a Telegram message that can't be split without breaking its markup
+type telegramUnit struct {
+	html string
+	text string
+	bold bool
+}
+
+// size counts like Telegram does, in UTF-16 units of the text without the markup
+func (unit telegramUnit) size() int {
+	return utf16Length(unit.text)
+}
+
+func utf16Length(text string) int {
+	size := 0
+	for _, char := range text {
+		size += utf16Units(char)
+	}
+	return size
+}
+
+// utf16Units is two for the characters outside the basic plane, like most emoji
+func utf16Units(char rune) int {
+	if char < 0x10000 {
+		return 1
+	}
+	return 2
+}
+
+// telegramBlocks are the same blocks Html joins, the day name and every item with its infos
+func (day WeekdayParsed) telegramBlocks() [][]telegramUnit {
+	blocks := make([][]telegramUnit, 0, len(day.Items)+1)
+	blocks = append(blocks, []telegramUnit{{htmlBold(html.EscapeString(day.Name)), day.Name, true}})
+	for _, item := range day.Items {
+		units := []telegramUnit{{htmlBold(html.EscapeString(item.Title())), item.Title(), true}}
+		for _, info := range item.Infos {
+			units = append(units, telegramUnit{
+				html: info.lines(day.AllDayName, htmlBold, html.EscapeString),
+				text: info.lines(day.AllDayName, plain, plain),
+			})
+		}
+		blocks = append(blocks, units)
+	}
+
+	return blocks
+}
+
+type telegramPacker struct {
+	limit    int
+	messages []string
+	html     strings.Builder
+	size     int
+}
+
+func (packer *telegramPacker) flush() {
+	if packer.size == 0 {
+		return
+	}
+	packer.messages = append(packer.messages, packer.html.String())
+	packer.html.Reset()
+	packer.size = 0
+}
+
+// add appends the unit after the separator, moving it to a new message when it doesn't fit
+func (packer *telegramPacker) add(separator string, unit telegramUnit) {
+	if packer.size > 0 && packer.size+utf16Length(separator)+unit.size() > packer.limit {
+		packer.flush()
+	}
+
+	// the last resort for a unit longer than a message, its text is cut before it's escaped
+	if unit.size() > packer.limit {
+		packer.flush()
+		pieces := cutUtf16(unit.text, packer.limit)
+		for _, piece := range pieces[:len(pieces)-1] {
+			packer.messages = append(packer.messages, telegramPiece(piece, unit.bold))
+		}
+		unit = telegramUnit{
+			html: telegramPiece(pieces[len(pieces)-1], unit.bold),
+			text: pieces[len(pieces)-1],
+		}
+	}
+
+	if packer.size > 0 {
+		packer.html.WriteString(separator)
+		packer.size += utf16Length(separator)
+	}
+	packer.html.WriteString(unit.html)
+	packer.size += unit.size()
+}
+
+func telegramPiece(text string, bold bool) string {
+	if bold {
+		return htmlBold(html.EscapeString(text))
+	}
+	return html.EscapeString(text)
+}
+
+func cutUtf16(text string, limit int) []string {
+	pieces := make([]string, 0, 2)
+
+	piece := new(strings.Builder)
+	size := 0
+	for _, char := range text {
+		if size+utf16Units(char) > limit {
+			pieces = append(pieces, piece.String())
+			piece.Reset()
+			size = 0
+		}
+		piece.WriteRune(char)
+		size += utf16Units(char)
+	}
+
+	return append(pieces, piece.String())
+}
+
+// TelegramPosts puts the whole week into as few messages as the text limit allows, pinning the first one.
+// Messages are split between days where they can be, and between lines where they can't, so no tag is cut in half
+func (week WeekParsed) TelegramPosts() []Post {
+	packer := telegramPacker{
+		limit: webhook.TelegramText,
+	}
+	for _, day := range []WeekdayParsed{week.Mon, week.Tue, week.Wed, week.Thu, week.Fri, week.Sat, week.Sun} {
+		if len(day.Items) == 0 {
+			continue
+		}
+
+		blocks := day.telegramBlocks()
+		size := 0
+		for blockIndex, block := range blocks {
+			if blockIndex > 0 {
+				size += 2
+			}
+			for unitIndex, unit := range block {
+				if unitIndex > 0 {
+					size++
+				}
+				size += unit.size()
+			}
+		}
+		// a day that fits into a message of its own isn't split
+		if packer.size > 0 && packer.size+2+size > packer.limit {
+			packer.flush()
+		}
+
+		for _, block := range blocks {
+			for index, unit := range block {
+				separator := "\n"
+				if index == 0 {
+					separator = "\n\n"
+				}
+				packer.add(separator, unit)
+			}
+		}
+	}
+	packer.flush()
+	if len(packer.messages) == 0 {
+		return nil
+	}
+
+	messages := make([]webhook.TelegramMessage, 0, len(packer.messages))
+	for index, text := range packer.messages {
+		messages = append(messages, webhook.TelegramMessage{
+			Text: text,
+			Pin:  index == 0,
+		})
+	}
+
+	return []Post{{
+		Key:      "week",
+		Messages: anyMessages(messages),
+	}}
+}
+
+type EmailSubject struct {
+	Name string
+	Date time.Time
+	Week WeekParsed
+}
+
+// EmailPosts puts the whole week into a single email, with the subject rendered from its template
+func (week WeekParsed) EmailPosts(subject *template.Template, name string) ([]Post, error) {
+	texts := make([]string, 0, len(dayKeys))
+	htmls := make([]string, 0, len(dayKeys))
+	for _, day := range []WeekdayParsed{week.Mon, week.Tue, week.Wed, week.Thu, week.Fri, week.Sat, week.Sun} {
+		if len(day.Items) == 0 {
+			continue
+		}
+		texts = append(texts, day.Text())
+		htmls = append(htmls, "<p>"+strings.ReplaceAll(day.Html(), "\n", "<br>\n")+"</p>")
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	title := new(strings.Builder)
+	if err := subject.Execute(title, EmailSubject{
+		Name: name,
+		Date: time.Now(),
+		Week: week,
+	}); err != nil {
+		return nil, err
+	}
+
+	return []Post{{
+		Key: "week",
+		Messages: []any{webhook.EmailMessage{
+			Subject: strings.TrimSpace(title.String()),
+			Text:    strings.Join(texts, "\n\n"),
+			Html:    "<!DOCTYPE html>\n<html>\n<body>\n" + strings.Join(htmls, "\n") + "\n</body>\n</html>\n",
+		}},
+	}}, nil
+}
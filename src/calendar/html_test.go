@@ -0,0 +1,102 @@
+package calendar
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+var (
+	telegramTag    = regexp.MustCompile(`</?b>`)
+	telegramEntity = regexp.MustCompile(`&(amp|lt|gt|quot|#39);`)
+)
+
+func telegramWeek(titles ...string) WeekParsed {
+	start := time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)
+	items := make([]ItemParsed, 0, len(titles))
+	for _, title := range titles {
+		items = append(items, ItemParsed{
+			Name: title,
+			Infos: []Info{{
+				Name:   "Room <5> & " + title,
+				Start:  []time.Time{start},
+				End:    []time.Time{start.Add(time.Hour)},
+				AllDay: []bool{false},
+			}},
+		})
+	}
+
+	return WeekParsed{
+		Mon: WeekdayParsed{Name: "Monday", Items: items},
+		Tue: WeekdayParsed{Name: "Tuesday", Items: items[:1]},
+	}
+}
+
+func telegramTexts(t *testing.T, week WeekParsed) []string {
+	t.Helper()
+
+	posts := week.TelegramPosts()
+	if len(posts) != 1 {
+		t.Fatalf("got %d posts, want 1", len(posts))
+	}
+	texts := make([]string, 0, len(posts[0].Messages))
+	for _, message := range posts[0].Messages {
+		texts = append(texts, message.(webhook.TelegramMessage).Text)
+	}
+	return texts
+}
+
+func TestTelegramPostsFit(t *testing.T) {
+	week := telegramWeek("Math", "Physics")
+	texts := telegramTexts(t, week)
+
+	if want := week.Mon.Html() + "\n\n" + week.Tue.Html(); len(texts) != 1 || texts[0] != want {
+		t.Errorf("texts = %q, want %q", texts, want)
+	}
+}
+
+func TestTelegramPostsSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		count int
+	}{
+		{"entities", strings.Repeat("R&D <lab> ", 20), 40},
+		// every emoji is two UTF-16 units but a single rune
+		{"emoji", strings.Repeat("📅🎉", 60), 40},
+		{"longer than a message", strings.Repeat("&📅", 3000), 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			titles := make([]string, test.count)
+			for index := range titles {
+				titles[index] = test.title
+			}
+			texts := telegramTexts(t, telegramWeek(titles...))
+			if len(texts) < 2 {
+				t.Fatalf("got %d messages, want the week split", len(texts))
+			}
+
+			for index, text := range texts {
+				opened := strings.Count(text, "<b>")
+				if closed := strings.Count(text, "</b>"); opened != closed {
+					t.Errorf("message %d has %d <b> and %d </b>", index, opened, closed)
+				}
+
+				stripped := telegramTag.ReplaceAllString(text, "")
+				if strings.ContainsAny(telegramEntity.ReplaceAllString(stripped, ""), "&<>") {
+					t.Errorf("message %d has a broken entity", index)
+				}
+				if size := len(utf16.Encode([]rune(html.UnescapeString(stripped)))); size > webhook.TelegramText {
+					t.Errorf("message %d is %d UTF-16 units long", index, size)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+// JsonPosts sends every day, even empty ones, so the receiver always gets the whole week
+func (week WeekParsed) JsonPosts() []Post {
+	jsonWeek := webhook.JsonWeek{
+		Days: make([]webhook.JsonDay, 0, len(dayKeys)),
+	}
+
+	days := week.Days()
+	for _, key := range dayKeys {
+		day := days[key]
+		jsonDay := webhook.JsonDay{
+			Key:   key,
+			Name:  day.Name,
+			Items: make([]webhook.JsonItem, 0, len(day.Items)),
+		}
+
+		for _, item := range day.Items {
+			jsonItem := webhook.JsonItem{
+				Name:    item.Name,
+				Label:   item.Label,
+				ColorId: item.ColorId,
+				Infos:   make([]webhook.JsonInfo, 0, len(item.Infos)),
+			}
+
+			for _, info := range item.Infos {
+				jsonInfo := webhook.JsonInfo{
+					Name:        info.Name,
+					Occurrences: make([]webhook.JsonOccurrence, 0, len(info.Start)),
+				}
+				for _, occurrence := range info.Occurrences() {
+					jsonInfo.Occurrences = append(jsonInfo.Occurrences, webhook.JsonOccurrence{
+						Start:  occurrence.Start.Format(time.RFC3339),
+						End:    occurrence.End.Format(time.RFC3339),
+						AllDay: occurrence.AllDay,
+					})
+				}
+				jsonItem.Infos = append(jsonItem.Infos, jsonInfo)
+			}
+			jsonDay.Items = append(jsonDay.Items, jsonItem)
+		}
+		jsonWeek.Days = append(jsonWeek.Days, jsonDay)
+	}
+
+	return []Post{{
+		Key:      "week",
+		Messages: []any{jsonWeek},
+	}}
+}
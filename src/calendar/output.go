@@ -0,0 +1,298 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/store"
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+var dayKeys = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+func (week WeekOutput) Days() map[string][]string {
+	return map[string][]string{
+		"mon": week.Mon,
+		"tue": week.Tue,
+		"wed": week.Wed,
+		"thu": week.Thu,
+		"fri": week.Fri,
+		"sat": week.Sat,
+		"sun": week.Sun,
+	}
+}
+
+func (week WeekOutput) Posts() []Post {
+	posts := make([]Post, 0, len(dayKeys))
+
+	days := week.Days()
+	for _, key := range dayKeys {
+		if len(days[key]) == 0 {
+			continue
+		}
+
+		post := Post{
+			Key:      key,
+			Messages: make([]any, 0, len(days[key])),
+		}
+		for _, content := range days[key] {
+			post.Messages = append(post.Messages, webhook.Message{
+				Content: content,
+			})
+		}
+		posts = append(posts, post)
+	}
+
+	return posts
+}
+
+func anyMessages[M any](messages []M) []any {
+	values := make([]any, 0, len(messages))
+	for _, message := range messages {
+		values = append(values, message)
+	}
+
+	return values
+}
+
+func renderPosts(week WeekParsed, tmpl *template.Template, render config.Render, limits webhook.Limits) ([]Post, error) {
+	if render.Embed.Enabled {
+		return week.Embeds(render.Embed, limits)
+	}
+
+	weekOutput, err := week.Stringify(tmpl, limits.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return weekOutput.Posts(), nil
+}
+
+// Body is what gets compared between checks, a single plain message keeps its content so older states stay valid
+func (post Post) Body() string {
+	if len(post.Messages) == 1 {
+		if message, ok := post.Messages[0].(webhook.Message); ok && len(message.Embeds) == 0 {
+			return message.Content
+		}
+	}
+
+	body, err := json.Marshal(post.Messages)
+	if err != nil {
+		return ""
+	}
+
+	return string(body)
+}
+
+func postsOutput(posts []Post) map[string]string {
+	output := make(map[string]string, len(posts))
+	for _, post := range posts {
+		output[post.Key] = post.Body()
+	}
+
+	return output
+}
+
+func outputHash(output map[string]string) string {
+	keys := make([]string, 0, len(output))
+	for key := range output {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		values = append(values, key, output[key])
+	}
+
+	return store.Hash(values...)
+}
+
+// stamp marks the last Discord embed with the time it was posted, outside of the compared body
+func stamp(message any) any {
+	discordMessage, ok := message.(webhook.Message)
+	if !ok || len(discordMessage.Embeds) == 0 {
+		return message
+	}
+
+	discordMessage.Embeds = slices.Clone(discordMessage.Embeds)
+	discordMessage.Embeds[len(discordMessage.Embeds)-1].Timestamp = time.Now().Format(time.RFC3339)
+	return discordMessage
+}
+
+func outputPost(ctx context.Context, notifier webhook.Notifier, post Post, state *store.State) error {
+	messageIds := state.Messages[post.Key]
+	body := post.Body()
+
+	if body == state.Output[post.Key] && len(messageIds) == len(post.Messages) {
+		log.Trace().
+			Str("post", post.Key).
+			Msg("Post is the same")
+		return nil
+	}
+
+	// remember every message that is already in place, even if a later one fails
+	postedIds := make([]string, 0, len(post.Messages))
+	defer func() {
+		if len(postedIds) < len(messageIds) {
+			postedIds = append(postedIds, messageIds[len(postedIds):]...)
+		}
+		state.Messages[post.Key] = postedIds
+	}()
+
+	for index, message := range post.Messages {
+		message = stamp(message)
+
+		if index < len(messageIds) {
+			log.Trace().
+				Str("post", post.Key).
+				Str("message", messageIds[index]).
+				Msg("Editing post")
+			err := notifier.Edit(ctx, messageIds[index], message)
+			if err == nil {
+				postedIds = append(postedIds, messageIds[index])
+				continue
+			}
+			switch {
+			case errors.Is(err, errors.ErrUnsupported):
+				log.Trace().
+					Str("post", post.Key).
+					Msg("Messages can't be edited, posting a new one")
+			case errors.Is(err, webhook.ErrNotFound):
+				log.Warn().
+					Str("post", post.Key).
+					Str("message", messageIds[index]).
+					Msg("Message was removed, posting a new one")
+			default:
+				return err
+			}
+		}
+
+		log.Trace().
+			Str("post", post.Key).
+			Msg("Posting")
+		messageId, err := notifier.Send(ctx, message)
+		if err != nil {
+			return err
+		}
+
+		postedIds = append(postedIds, messageId)
+		if messageId != "" {
+			state.Created = append(state.Created, messageId)
+		}
+	}
+
+	if len(messageIds) > len(post.Messages) {
+		log.Trace().
+			Str("post", post.Key).
+			Msg("Post got shorter, deleting its leftover messages")
+		if err := deleteMessages(ctx, notifier, messageIds[len(post.Messages):], state); err != nil {
+			return err
+		}
+		messageIds = messageIds[:len(post.Messages)]
+	}
+
+	state.Output[post.Key] = body
+	return nil
+}
+
+func deleteMessages(ctx context.Context, notifier webhook.Notifier, messageIds []string, state *store.State) error {
+	for _, messageId := range slices.Clone(messageIds) {
+		if messageId == "" {
+			continue
+		}
+		if err := notifier.Delete(ctx, messageId); err != nil && !errors.Is(err, webhook.ErrNotFound) && !errors.Is(err, errors.ErrUnsupported) {
+			return err
+		}
+
+		state.Created = slices.DeleteFunc(state.Created, func(createdId string) bool {
+			return createdId == messageId
+		})
+	}
+
+	return nil
+}
+
+func outputPosts(ctx context.Context, notifier webhook.Notifier, cleanup string, posts []Post, state *store.State) error {
+	switch cleanup {
+	case "", "edit":
+		// posts are edited in place
+	case "replace":
+		log.Trace().
+			Int("messages", len(state.Created)).
+			Msg("Deleting previously posted messages")
+		state.Messages = make(map[string][]string)
+		state.Output = make(map[string]string)
+		if err := deleteMessages(ctx, notifier, state.Created, state); err != nil {
+			return err
+		}
+	case "append":
+		// old messages are kept, but are no longer tied to a post
+		state.Messages = make(map[string][]string)
+		state.Output = make(map[string]string)
+		// nor are they ever deleted, so only the latest messages are remembered for a switch to replace
+		state.Created = nil
+	default:
+		return fmt.Errorf("unknown cleanup mode %s", cleanup)
+	}
+
+	output := postsOutput(posts)
+	for key, messageIds := range state.Messages {
+		if _, found := output[key]; !found {
+			log.Trace().
+				Str("post", key).
+				Msg("Post is now empty, deleting its messages")
+			if err := deleteMessages(ctx, notifier, messageIds, state); err != nil {
+				return err
+			}
+
+			delete(state.Messages, key)
+			delete(state.Output, key)
+		}
+	}
+
+	for _, post := range posts {
+		if err := outputPost(ctx, notifier, post, state); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getState(st store.Store, key string) (store.State, error) {
+	state := store.State{}
+
+	if _, err := st.Get(key, &state); err != nil {
+		return store.State{
+			Output:   make(map[string]string),
+			Messages: make(map[string][]string),
+		}, err
+	}
+
+	if state.Output == nil {
+		state.Output = make(map[string]string)
+	}
+	if state.Messages == nil {
+		state.Messages = make(map[string][]string)
+	}
+
+	return state, nil
+}
+
+// saveState stores whatever was actually posted, so a partially failed output is retried on the next check
+func saveState(st store.Store, key string, state store.State) error {
+	state.Hash = outputHash(state.Output)
+	state.UpdatedAt = time.Now()
+
+	return st.Set(key, state)
+}
@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aleksasiriski/smerac-go/src/store"
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+// fakeNotifier numbers the messages it sends and remembers which ones still exist
+type fakeNotifier struct {
+	next    int
+	posted  []string
+	deleted []string
+}
+
+func (notifier *fakeNotifier) Send(ctx context.Context, message any) (string, error) {
+	notifier.next++
+	messageId := fmt.Sprint(notifier.next)
+	notifier.posted = append(notifier.posted, messageId)
+	return messageId, nil
+}
+
+func (notifier *fakeNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	return nil
+}
+
+func (notifier *fakeNotifier) Delete(ctx context.Context, messageId string) error {
+	notifier.deleted = append(notifier.deleted, messageId)
+	notifier.posted = slices.DeleteFunc(notifier.posted, func(postedId string) bool {
+		return postedId == messageId
+	})
+	return nil
+}
+
+func weekPosts(content string) []Post {
+	posts := make([]Post, 0, 2)
+	for _, day := range []string{"mon", "tue"} {
+		posts = append(posts, Post{
+			Key: day,
+			Messages: []any{webhook.Message{
+				Content: day + " " + content,
+			}},
+		})
+	}
+	return posts
+}
+
+func TestAppendThenReplace(t *testing.T) {
+	notifier := &fakeNotifier{}
+	state := store.State{
+		Output:   make(map[string]string),
+		Messages: make(map[string][]string),
+	}
+
+	for week := 1; week <= 3; week++ {
+		if err := outputPosts(context.Background(), notifier, "append", weekPosts(fmt.Sprint(week)), &state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the latest week is remembered, older appended ones are kept for good
+	if want := []string{"5", "6"}; !slices.Equal(state.Created, want) {
+		t.Errorf("created = %v, want %v", state.Created, want)
+	}
+
+	if err := outputPosts(context.Background(), notifier, "replace", weekPosts("4"), &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"5", "6"}; !slices.Equal(notifier.deleted, want) {
+		t.Errorf("deleted = %v, want the last appended week %v", notifier.deleted, want)
+	}
+	if want := []string{"1", "2", "3", "4", "7", "8"}; !slices.Equal(notifier.posted, want) {
+		t.Errorf("posted = %v, want %v", notifier.posted, want)
+	}
+	if want := []string{"7", "8"}; !slices.Equal(state.Created, want) {
+		t.Errorf("created = %v, want %v", state.Created, want)
+	}
+}
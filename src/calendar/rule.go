@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+type Rule struct {
+	separator string
+	regex     *regexp.Regexp
+	nameField string
+	infoField string
+}
+
+func NewRule(conf config.ParseRule) (Rule, error) {
+	rule := Rule{
+		separator: conf.Separator,
+		nameField: strings.ToLower(conf.Name),
+		infoField: strings.ToLower(conf.Info),
+	}
+
+	if rule.separator == "" {
+		rule.separator = ","
+	}
+	if rule.nameField == "" {
+		rule.nameField = "summary"
+	}
+
+	if conf.Regex != "" {
+		regex, err := regexp.Compile(conf.Regex)
+		if err != nil {
+			return rule, err
+		}
+		if regex.SubexpIndex("name") == -1 && regex.SubexpIndex("info") == -1 {
+			return rule, errors.New("parse regex has neither a name nor an info group")
+		}
+		rule.regex = regex
+	}
+
+	return rule, nil
+}
+
+func (event Event) Field(field string) string {
+	switch field {
+	case "location":
+		return event.Location
+	case "description":
+		return event.Description
+	case "colorid", "color":
+		return event.ColorId
+	default:
+		return event.Summary
+	}
+}
+
+// Apply returns the group name and the info line of an event, falling back to the whole field as the name
+func (rule Rule) Apply(event Event) (string, string) {
+	text := event.Field(rule.nameField)
+
+	name, info := text, ""
+	switch {
+	case rule.regex != nil:
+		if match := rule.regex.FindStringSubmatch(text); match != nil {
+			if index := rule.regex.SubexpIndex("name"); index != -1 && match[index] != "" {
+				name = match[index]
+			}
+			if index := rule.regex.SubexpIndex("info"); index != -1 {
+				info = match[index]
+			}
+		}
+	case rule.infoField != "" && rule.infoField != rule.nameField:
+		info = event.Field(rule.infoField)
+	default:
+		// without a name part the text stays whole, or ",Room" would show "Room" twice
+		if nameinfo := strings.SplitN(text, rule.separator, 2); len(nameinfo) == 2 && strings.TrimSpace(nameinfo[0]) != "" {
+			name, info = nameinfo[0], nameinfo[1]
+		}
+		// the split isn't trimmed, so "Math, Room 5" keeps rendering as it always has
+		if strings.TrimSpace(name) == "" {
+			name = event.Summary
+		}
+		return name, info
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = strings.TrimSpace(event.Summary)
+	}
+
+	return name, strings.TrimSpace(info)
+}
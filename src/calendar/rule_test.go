@@ -0,0 +1,35 @@
+package calendar
+
+import (
+	"testing"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/source"
+)
+
+func TestRuleApplySeparator(t *testing.T) {
+	rule, err := NewRule(config.ParseRule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		summary string
+		name    string
+		info    string
+	}{
+		{"Math, Room 5", "Math", " Room 5"},
+		{"Math", "Math", ""},
+		{",Room", ",Room", ""},
+		{" , Room", " , Room", ""},
+		{"Math,", "Math", ""},
+		{",", ",", ""},
+	}
+
+	for _, test := range tests {
+		name, info := rule.Apply(Event{Event: source.Event{Summary: test.summary}})
+		if name != test.name || info != test.info {
+			t.Errorf("%q = (%q, %q), want (%q, %q)", test.summary, name, info, test.name, test.info)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package calendar
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+type Schedule struct {
+	cron     cron.Schedule
+	every    time.Duration
+	location *time.Location
+	jitter   time.Duration
+}
+
+// NewSchedule accepts a Go duration or a cron expression, falling back to the number of hours between checks
+func NewSchedule(expression string, timezone string, jitter string, hours int8) (Schedule, error) {
+	schedule := Schedule{
+		location: time.Local,
+	}
+
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return schedule, err
+		}
+		schedule.location = location
+	}
+
+	if jitter != "" {
+		duration, err := time.ParseDuration(jitter)
+		if err != nil {
+			return schedule, fmt.Errorf("invalid jitter %s: %w", jitter, err)
+		}
+		schedule.jitter = duration
+	}
+
+	switch {
+	case expression == "" && hours > 0:
+		schedule.every = time.Hour * time.Duration(hours)
+	case expression == "":
+		schedule.every = time.Hour * 3
+	default:
+		if duration, err := time.ParseDuration(expression); err == nil {
+			if duration <= 0 {
+				return schedule, fmt.Errorf("schedule %s isn't positive", expression)
+			}
+			schedule.every = duration
+		} else if schedule.cron, err = cron.ParseStandard(expression); err != nil {
+			return schedule, fmt.Errorf("invalid schedule %s: %w", expression, err)
+		}
+	}
+
+	return schedule, nil
+}
+
+func (schedule Schedule) Next(now time.Time) time.Time {
+	next := now.Add(schedule.every)
+	if schedule.cron != nil {
+		next = schedule.cron.Next(now.In(schedule.location))
+	}
+
+	if schedule.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(schedule.jitter))))
+	}
+
+	return next
+}
@@ -0,0 +1,76 @@
+package calendar
+
+import (
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+func slackBold(text string) string {
+	return "*" + text + "*"
+}
+
+// SlackBlocks renders the day as a header followed by a section for every item
+func (day WeekdayParsed) SlackBlocks() []webhook.SlackBlock {
+	if len(day.Items) == 0 {
+		return nil
+	}
+
+	blocks := make([]webhook.SlackBlock, 0, len(day.Items)+1)
+	blocks = append(blocks, webhook.SlackBlock{
+		Type: "header",
+		Text: &webhook.SlackText{
+			Type: "plain_text",
+			Text: truncate(webhook.SlackHeaderText, day.Name),
+		},
+	})
+
+	for _, item := range day.Items {
+		infos := make([]string, 0, len(item.Infos))
+		for _, info := range item.Infos {
+			infos = append(infos, info.lines(day.AllDayName, slackBold, webhook.SlackEscape))
+		}
+
+		title := slackBold(webhook.SlackEscape(truncate(webhook.SlackHeaderText, item.Title())))
+		for _, value := range fieldValues(infos, webhook.SlackSectionText-length(title)-1) {
+			blocks = append(blocks, webhook.SlackBlock{
+				Type: "section",
+				Text: &webhook.SlackText{
+					Type: "mrkdwn",
+					Text: title + "\n" + value,
+				},
+			})
+		}
+	}
+
+	return blocks
+}
+
+// SlackPosts makes a post for every day with events, split into as many messages as the block limit needs
+func (week WeekParsed) SlackPosts() []Post {
+	posts := make([]Post, 0, len(dayKeys))
+
+	days := week.Days()
+	for _, key := range dayKeys {
+		blocks := days[key].SlackBlocks()
+		if len(blocks) == 0 {
+			continue
+		}
+
+		messages := make([]webhook.SlackMessage, 0, 1)
+		for len(blocks) > 0 {
+			count := min(len(blocks), webhook.SlackBlocks)
+			messages = append(messages, webhook.SlackMessage{
+				// shown in notifications, where blocks aren't
+				Text:   days[key].Name,
+				Blocks: blocks[:count],
+			})
+			blocks = blocks[count:]
+		}
+
+		posts = append(posts, Post{
+			Key:      key,
+			Messages: anyMessages(messages),
+		})
+	}
+
+	return posts
+}
@@ -0,0 +1,95 @@
+package calendar
+
+import (
+	"slices"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+func length(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// splitLines is the last resort for text that doesn't fit even when split at item boundaries
+func splitLines(text string, limit int) []string {
+	chunks := make([]string, 0, 1)
+
+	chunk := ""
+	for _, line := range strings.Split(text, "\n") {
+		for length(line) > limit {
+			if chunk != "" {
+				chunks = append(chunks, chunk)
+				chunk = ""
+			}
+			runes := []rune(line)
+			chunks = append(chunks, string(runes[:limit]))
+			line = string(runes[limit:])
+		}
+
+		switch {
+		case chunk == "":
+			chunk = line
+		case length(chunk)+1+length(line) <= limit:
+			chunk += "\n" + line
+		default:
+			chunks = append(chunks, chunk)
+			chunk = line
+		}
+	}
+	if strings.TrimSpace(chunk) != "" {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// Split renders the day into as many messages as needed for each of them to fit into the limit, splitting at item boundaries
+func (day WeekdayParsed) Split(tmpl *template.Template, week WeekParsed, limit int) ([]string, error) {
+	whole, err := day.Stringify(tmpl, week)
+	if err != nil || whole == "" {
+		return nil, err
+	}
+	if limit <= 0 || length(whole) <= limit {
+		return []string{whole}, nil
+	}
+
+	chunks := make([]string, 0, 2)
+
+	chunk := day
+	chunk.Items = nil
+	rendered := ""
+	for _, item := range day.Items {
+		candidate := chunk
+		candidate.Items = append(slices.Clone(chunk.Items), item)
+
+		text, err := candidate.Stringify(tmpl, week)
+		if err != nil {
+			return nil, err
+		}
+		if length(text) <= limit {
+			chunk = candidate
+			rendered = text
+			continue
+		}
+
+		if len(chunk.Items) > 0 {
+			chunks = append(chunks, rendered)
+		}
+
+		chunk.Items = []ItemParsed{item}
+		if rendered, err = chunk.Stringify(tmpl, week); err != nil {
+			return nil, err
+		}
+		if length(rendered) > limit {
+			chunks = append(chunks, splitLines(rendered, limit)...)
+			chunk.Items = nil
+			rendered = ""
+		}
+	}
+	if len(chunk.Items) > 0 {
+		chunks = append(chunks, rendered)
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,66 @@
+package calendar
+
+import (
+	"strings"
+
+	"github.com/aleksasiriski/smerac-go/src/webhook"
+)
+
+// AdaptiveBlocks renders the day as text blocks, one per line so clients agree on the line breaks
+func (day WeekdayParsed) AdaptiveBlocks() []webhook.AdaptiveBlock {
+	if len(day.Items) == 0 {
+		return nil
+	}
+
+	blocks := make([]webhook.AdaptiveBlock, 0, len(day.Items)*3+1)
+	blocks = append(blocks, webhook.AdaptiveBlock{
+		Type:   "TextBlock",
+		Text:   day.Name,
+		Size:   "Large",
+		Weight: "Bolder",
+		Wrap:   true,
+	})
+
+	for _, item := range day.Items {
+		blocks = append(blocks, webhook.AdaptiveBlock{
+			Type:      "TextBlock",
+			Text:      item.Title(),
+			Weight:    "Bolder",
+			Wrap:      true,
+			Separator: true,
+		})
+
+		for _, info := range item.Infos {
+			for _, line := range strings.Split(info.Lines(day.AllDayName), "\n") {
+				blocks = append(blocks, webhook.AdaptiveBlock{
+					Type:    "TextBlock",
+					Text:    line,
+					Wrap:    true,
+					Spacing: "None",
+				})
+			}
+		}
+	}
+
+	return blocks
+}
+
+// TeamsPosts makes a post with an Adaptive Card for every day with events
+func (week WeekParsed) TeamsPosts() []Post {
+	posts := make([]Post, 0, len(dayKeys))
+
+	days := week.Days()
+	for _, key := range dayKeys {
+		blocks := days[key].AdaptiveBlocks()
+		if len(blocks) == 0 {
+			continue
+		}
+
+		posts = append(posts, Post{
+			Key:      key,
+			Messages: []any{webhook.NewTeamsMessage(blocks)},
+		})
+	}
+
+	return posts
+}
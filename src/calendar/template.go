@@ -0,0 +1,98 @@
+package calendar
+
+import (
+	"os"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+const defaultTemplate = `-------------------------
+
+**{{ .Name }}:**
+
+{{ range .Items -}}
+--- **{{ .Name }}**{{ if .Label }} ({{ .Label }}){{ end }} ---
+{{ range .Infos -}}
+{{ if .Name }}{{ .Name }}
+{{ end -}}
+{{ range .Occurrences -}}
+{{ if .AllDay }}**{{ $.AllDayName }}**{{ else }}**{{ formatTime .Start "15:04" }}** - {{ formatTime .End "15:04" }}{{ end }}
+{{ end -}}
+{{ end }}
+{{ end -}}
+-------------------------`
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`*`, `\*`,
+	`_`, `\_`,
+	`~`, `\~`,
+	"`", "\\`",
+	`|`, `\|`,
+	`>`, `\>`,
+	`#`, `\#`,
+)
+
+var templateFuncs = template.FuncMap{
+	"formatTime": func(moment time.Time, layout string) string {
+		return moment.Format(layout)
+	},
+	"escape": func(text string) string {
+		return markdownEscaper.Replace(text)
+	},
+	"truncate": truncate,
+}
+
+func truncate(length int, text string) string {
+	if utf8.RuneCountInString(text) <= length {
+		return text
+	}
+	if length <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	return string(runes[:length-1]) + "…"
+}
+
+func NewTemplate(conf config.Render) (*template.Template, error) {
+	text := defaultTemplate
+	switch {
+	case conf.TemplateFile != "":
+		data, err := os.ReadFile(conf.TemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	case conf.Template != "":
+		text = conf.Template
+	}
+
+	return template.New("day").Funcs(templateFuncs).Parse(text)
+}
+
+func (info Info) Occurrences() []Occurrence {
+	occurrences := make([]Occurrence, len(info.Start))
+	for index := range info.Start {
+		occurrences[index] = Occurrence{
+			Start:  info.Start[index],
+			End:    info.End[index],
+			AllDay: info.AllDay[index],
+		}
+	}
+
+	return occurrences
+}
+
+// Title is the item name followed by the source it came from, when tagged
+func (item ItemParsed) Title() string {
+	if item.Label == "" {
+		return item.Name
+	}
+
+	return item.Name + " (" + item.Label + ")"
+}
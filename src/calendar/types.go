@@ -3,38 +3,58 @@ package calendar
 import (
 	"time"
 
-	"google.golang.org/api/calendar/v3"
+	"github.com/aleksasiriski/smerac-go/src/source"
 )
 
-type Weekday struct {
+type Event struct {
+	source.Event
 	Name  string
-	Items []*calendar.Event
+	Info  string
+	Label string
+}
+
+type Weekday struct {
+	Name       string
+	AllDayName string
+	Items      []Event
 }
 
 type Info struct {
-	Name  string
-	Start []time.Time
-	End   []time.Time
+	Name   string
+	Start  []time.Time
+	End    []time.Time
+	AllDay []bool
+}
+
+type Occurrence struct {
+	Start  time.Time
+	End    time.Time
+	AllDay bool
 }
 
 type Week struct {
-	Mon Weekday
-	Tue Weekday
-	Wed Weekday
-	Thu Weekday
-	Fri Weekday
-	Sat Weekday
-	Sun Weekday
+	Start time.Time
+	End   time.Time
+	Mon   Weekday
+	Tue   Weekday
+	Wed   Weekday
+	Thu   Weekday
+	Fri   Weekday
+	Sat   Weekday
+	Sun   Weekday
 }
 
 type ItemParsed struct {
-	Name  string
-	Infos []Info
+	Name    string
+	Label   string
+	ColorId string
+	Infos   []Info
 }
 
 type WeekdayParsed struct {
-	Name  string
-	Items []ItemParsed
+	Name       string
+	AllDayName string
+	Items      []ItemParsed
 }
 
 type WeekParsed struct {
@@ -48,11 +68,29 @@ type WeekParsed struct {
 }
 
 type WeekOutput struct {
-	Mon string
-	Tue string
-	Wed string
-	Thu string
-	Fri string
-	Sat string
-	Sun string
+	Mon []string
+	Tue []string
+	Wed []string
+	Thu []string
+	Fri []string
+	Sat []string
+	Sun []string
+}
+
+type DayTemplate struct {
+	WeekdayParsed
+	Week WeekParsed
+}
+
+// Origin is one of the sources a calendar merges, with its own parse rule
+type Origin struct {
+	Source source.Source
+	Rule   Rule
+	Label  string
+}
+
+// Post is a group of messages kept together, each one rendered for the notifier it goes to
+type Post struct {
+	Key      string
+	Messages []any
 }
@@ -2,7 +2,11 @@ package config
 
 func New() *Config {
 	return &Config{
-		Google:    Google{},
+		Google: Google{
+			Push: Push{
+				Listen: ":8080",
+			},
+		},
 		Calendars: []Calendar{},
 		Days: NamedDays{
 			Monday:    "Monday",
@@ -12,6 +16,16 @@ func New() *Config {
 			Friday:    "Friday",
 			Saturday:  "Saturday",
 			Sunday:    "Sunday",
+			AllDay:    "All day",
+		},
+		Store: Store{
+			Type: "file",
+		},
+		Retry: Retry{
+			Attempts:   5,
+			Deadline:   "2m",
+			MinBackoff: "1s",
+			MaxBackoff: "30s",
 		},
 	}
 }
@@ -49,4 +49,120 @@ func (c *Config) Load(dataDirPath string, logDirPath string) {
 	if err := k.Unmarshal("", &c); err != nil {
 		log.Panic().Err(err).Msg("failed unmarshaling koanf config")
 	}
+
+	for index := range c.Calendars {
+		calendar := &c.Calendars[index]
+
+		// A single source is the same as a list with just that one
+		if len(calendar.Sources) == 0 {
+			calendar.Sources = []Source{calendar.Source}
+		}
+
+		for sourceIndex := range calendar.Sources {
+			source := &calendar.Sources[sourceIndex]
+
+			// A calendar without a source is a Google calendar configured by its id
+			if source.Type == "" && source.Url == "" && source.File == "" {
+				source.Type = "google"
+			}
+			if source.Type == "google" {
+				if source.Id == "" {
+					source.Id = calendar.Id
+				}
+				if source.Auth == "" {
+					source.Auth = calendar.Auth
+				}
+			}
+			if source.Type == "" {
+				source.Type = "ics"
+			}
+
+			// Sources without their own parse rule use the calendar's
+			if source.Parse == (ParseRule{}) {
+				source.Parse = calendar.Parse
+			}
+
+			// Files are relative to the config folder
+			source.File = relativePath(dataDirPath, source.File)
+		}
+
+		// A single webhook is the same as a list with just that one
+		if len(calendar.Destinations) == 0 {
+			calendar.Destinations = []Destination{{
+				Webhook: calendar.Webhook,
+				Cleanup: calendar.Cleanup,
+				Render:  calendar.Render,
+			}}
+		}
+
+		for destinationIndex := range calendar.Destinations {
+			destination := &calendar.Destinations[destinationIndex]
+
+			// Day names that aren't overridden come from the global ones
+			fillDays(&destination.Days, c.Days)
+
+			if destination.Timezone == "" {
+				destination.Timezone = calendar.Timezone
+			}
+
+			// Retries that aren't overridden follow the global policy
+			if destination.Retry.Attempts == 0 {
+				destination.Retry.Attempts = c.Retry.Attempts
+			}
+			if destination.Retry.Deadline == "" {
+				destination.Retry.Deadline = c.Retry.Deadline
+			}
+			if destination.Retry.MinBackoff == "" {
+				destination.Retry.MinBackoff = c.Retry.MinBackoff
+			}
+			if destination.Retry.MaxBackoff == "" {
+				destination.Retry.MaxBackoff = c.Retry.MaxBackoff
+			}
+
+			destination.Render.TemplateFile = relativePath(dataDirPath, destination.Render.TemplateFile)
+		}
+	}
+	c.Google.ServiceAccount.File = relativePath(dataDirPath, c.Google.ServiceAccount.File)
+	c.Google.OAuth.CredentialsFile = relativePath(dataDirPath, c.Google.OAuth.CredentialsFile)
+
+	// Keep the state store next to the config unless told otherwise
+	if c.Store.Path == "" {
+		switch c.Store.Type {
+		case "bolt", "bbolt", "db":
+			c.Store.Path = path.Join(dataDirPath, "smerac_state.db")
+		default:
+			c.Store.Path = path.Join(dataDirPath, "smerac_state.json")
+		}
+	}
+	c.Store.Path = relativePath(dataDirPath, c.Store.Path)
+}
+
+func relativePath(dataDirPath string, filePath string) string {
+	if filePath == "" || path.IsAbs(filePath) {
+		return filePath
+	}
+
+	return path.Join(dataDirPath, filePath)
+}
+
+func fillDays(days *NamedDays, fallback NamedDays) {
+	fields := []struct {
+		value    *string
+		fallback string
+	}{
+		{&days.Monday, fallback.Monday},
+		{&days.Tuesday, fallback.Tuesday},
+		{&days.Wednesday, fallback.Wednesday},
+		{&days.Thursday, fallback.Thursday},
+		{&days.Friday, fallback.Friday},
+		{&days.Saturday, fallback.Saturday},
+		{&days.Sunday, fallback.Sunday},
+		{&days.AllDay, fallback.AllDay},
+	}
+
+	for _, field := range fields {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+	}
 }
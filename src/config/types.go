@@ -1,7 +1,31 @@
 package config
 
+type ServiceAccount struct {
+	File    string `koanf:"file"`
+	Json    string `koanf:"json"`
+	Subject string `koanf:"subject"`
+}
+
+type OAuth struct {
+	ClientId        string `koanf:"client_id"`
+	ClientSecret    string `koanf:"client_secret"`
+	CredentialsFile string `koanf:"credentials_file"`
+	RefreshToken    string `koanf:"refresh_token"`
+	RedirectUrl     string `koanf:"redirect_url"`
+}
+
+type Push struct {
+	Enabled bool   `koanf:"enabled"`
+	Listen  string `koanf:"listen"`
+	Address string `koanf:"address"`
+	Token   string `koanf:"token"`
+}
+
 type Google struct {
-	Token string `koanf:"token"`
+	Token          string         `koanf:"token"`
+	ServiceAccount ServiceAccount `koanf:"service_account"`
+	OAuth          OAuth          `koanf:"oauth"`
+	Push           Push           `koanf:"push"`
 }
 
 type NamedDays struct {
@@ -12,17 +36,118 @@ type NamedDays struct {
 	Friday    string `koanf:"fri"`
 	Saturday  string `koanf:"sat"`
 	Sunday    string `koanf:"sun"`
+	AllDay    string `koanf:"allday"`
+}
+
+type ParseRule struct {
+	Separator string `koanf:"separator"`
+	Regex     string `koanf:"regex"`
+	Name      string `koanf:"name"`
+	Info      string `koanf:"info"`
+}
+
+type Embed struct {
+	Enabled     bool              `koanf:"enabled"`
+	Color       string            `koanf:"color"`
+	EventColors map[string]string `koanf:"event_colors"`
+	Footer      string            `koanf:"footer"`
+}
+
+type Render struct {
+	Template     string `koanf:"template"`
+	TemplateFile string `koanf:"template_file"`
+	Embed        Embed  `koanf:"embed"`
+}
+
+type Source struct {
+	Label    string    `koanf:"label"`
+	Type     string    `koanf:"type"`
+	Id       string    `koanf:"id"`
+	Auth     string    `koanf:"auth"`
+	Url      string    `koanf:"url"`
+	File     string    `koanf:"file"`
+	Username string    `koanf:"username"`
+	Password string    `koanf:"password"`
+	Token    string    `koanf:"token"`
+	Parse    ParseRule `koanf:"parse"`
+}
+
+type Mention struct {
+	Match    string   `koanf:"match"`
+	Roles    []string `koanf:"roles"`
+	Users    []string `koanf:"users"`
+	Everyone bool     `koanf:"everyone"`
+}
+
+type Smtp struct {
+	Host     string `koanf:"host"`
+	Port     int    `koanf:"port"`
+	Security string `koanf:"security"`
+	Username string `koanf:"username"`
+	Password string `koanf:"password"`
+	From     string `koanf:"from"`
+}
+
+type Retry struct {
+	Attempts   int    `koanf:"attempts"`
+	Deadline   string `koanf:"deadline"`
+	MinBackoff string `koanf:"min_backoff"`
+	MaxBackoff string `koanf:"max_backoff"`
+}
+
+type Destination struct {
+	Type            string            `koanf:"type"`
+	Webhook         string            `koanf:"webhook"`
+	Homeserver      string            `koanf:"homeserver"`
+	Room            string            `koanf:"room"`
+	Token           string            `koanf:"token"`
+	Api             string            `koanf:"api"`
+	Chat            string            `koanf:"chat"`
+	Pin             bool              `koanf:"pin"`
+	Smtp            Smtp              `koanf:"smtp"`
+	Recipients      []string          `koanf:"recipients"`
+	Subject         string            `koanf:"subject"`
+	Schedule        string            `koanf:"schedule"`
+	Timezone        string            `koanf:"timezone"`
+	Headers         map[string]string `koanf:"headers"`
+	Secret          string            `koanf:"secret"`
+	SignatureHeader string            `koanf:"signature_header"`
+	Username        string            `koanf:"username"`
+	IconUrl         string            `koanf:"icon_url"`
+	Cleanup         string            `koanf:"cleanup"`
+	Render          Render            `koanf:"render"`
+	Days            NamedDays         `koanf:"days"`
+	Mentions        []Mention         `koanf:"mentions"`
+	Retry           Retry             `koanf:"retry"`
 }
 
 type Calendar struct {
-	Id                string `koanf:"id"`
-	Webhook           string `koanf:"webhook"`
-	Name              string `koanf:"name"`
-	TimeBetweenChecks int8   `koanf:"time"`
+	Id                string        `koanf:"id"`
+	Webhook           string        `koanf:"webhook"`
+	Auth              string        `koanf:"auth"`
+	Source            Source        `koanf:"source"`
+	Sources           []Source      `koanf:"sources"`
+	Tag               bool          `koanf:"tag"`
+	Name              string        `koanf:"name"`
+	TimeBetweenChecks int8          `koanf:"time"`
+	Schedule          string        `koanf:"schedule"`
+	Timezone          string        `koanf:"timezone"`
+	Jitter            string        `koanf:"jitter"`
+	Cleanup           string        `koanf:"cleanup"`
+	Parse             ParseRule     `koanf:"parse"`
+	Render            Render        `koanf:"render"`
+	Destinations      []Destination `koanf:"destinations"`
+}
+
+type Store struct {
+	Type string `koanf:"type"`
+	Path string `koanf:"path"`
 }
 
 type Config struct {
 	Google    Google     `koanf:"google"`
 	Calendars []Calendar `koanf:"calendars"`
 	Days      NamedDays  `koanf:"days"`
+	Store     Store      `koanf:"store"`
+	Retry     Retry      `koanf:"retry"`
 }
@@ -5,11 +5,15 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata"
+
+	"github.com/rs/zerolog/log"
 
 	"github.com/aleksasiriski/smerac-go/src/calendar"
 	"github.com/aleksasiriski/smerac-go/src/cli"
 	"github.com/aleksasiriski/smerac-go/src/config"
 	"github.com/aleksasiriski/smerac-go/src/logger"
+	"github.com/aleksasiriski/smerac-go/src/store"
 )
 
 func main() {
@@ -17,7 +21,8 @@ func main() {
 	cliFlags := cli.Setup()
 
 	// signal interrupt (CTRL+C)
-	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// configure logging
 	logger.Setup(cliFlags.LogDirPath, cliFlags.Verbosity)
@@ -26,6 +31,13 @@ func main() {
 	conf := config.New()
 	conf.Load(cliFlags.ConfigDirPath, cliFlags.LogDirPath)
 
+	// open state store
+	st, err := store.New(conf.Store.Type, conf.Store.Path)
+	if err != nil {
+		log.Panic().Err(err).Msg("failed opening state store")
+	}
+	defer st.Close()
+
 	// startup
-	calendar.Update(ctx, conf)
+	calendar.Update(ctx, conf, st)
 }
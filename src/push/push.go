@@ -0,0 +1,216 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/source"
+)
+
+// renewBefore is how long before expiry a channel is replaced
+const renewBefore = time.Hour
+
+// retryAfter is how long to wait before registering again after a failure
+const retryAfter = 5 * time.Minute
+
+type Receiver struct {
+	listen   string
+	address  string
+	token    string
+	mutex    sync.Mutex
+	triggers map[string]chan<- struct{}
+}
+
+// New returns nil when push notifications are disabled
+func New(conf config.Push) (*Receiver, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	if conf.Address == "" {
+		return nil, errors.New("push address is required")
+	}
+
+	token := conf.Token
+	if token == "" {
+		var err error
+		if token, err = randomId(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Receiver{
+		listen:   conf.Listen,
+		address:  conf.Address,
+		token:    token,
+		triggers: make(map[string]chan<- struct{}),
+	}, nil
+}
+
+func randomId() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+func (receiver *Receiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := request.Header.Get("X-Goog-Channel-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(receiver.token)) != 1 {
+		log.Warn().
+			Str("remote", request.RemoteAddr).
+			Msg("Rejected notification with an invalid token")
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	channelId := request.Header.Get("X-Goog-Channel-ID")
+	state := request.Header.Get("X-Goog-Resource-State")
+
+	receiver.mutex.Lock()
+	trigger, found := receiver.triggers[channelId]
+	receiver.mutex.Unlock()
+
+	if !found {
+		// unknown channels are left to expire on their own
+		log.Debug().
+			Str("channel", channelId).
+			Msg("Notification for an unknown channel")
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	log.Trace().
+		Str("channel", channelId).
+		Str("state", state).
+		Msg("Received notification")
+
+	// the first "sync" message only confirms the channel
+	if state != "sync" {
+		// a refresh that is already pending covers this change too
+		select {
+		case trigger <- struct{}{}:
+		default:
+		}
+	}
+
+	writer.WriteHeader(http.StatusOK)
+}
+
+// Listen serves notifications until the context is cancelled
+func (receiver *Receiver) Listen(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              receiver.listen,
+		Handler:           receiver,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Info().
+		Str("listen", receiver.listen).
+		Msg("Listening for notifications")
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (receiver *Receiver) register(channelId string, trigger chan<- struct{}) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.triggers[channelId] = trigger
+}
+
+func (receiver *Receiver) unregister(channelId string) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	delete(receiver.triggers, channelId)
+}
+
+// Watch keeps a channel registered for the source until the context is cancelled,
+// sending on the trigger whenever it reports a change
+func (receiver *Receiver) Watch(ctx context.Context, name string, watcher source.Watcher, trigger chan<- struct{}) {
+	var current *source.Channel
+	defer func() {
+		if current == nil {
+			return
+		}
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		receiver.stop(stopCtx, name, watcher, *current)
+	}()
+
+	for {
+		next := retryAfter
+		if channel, err := receiver.renew(ctx, watcher, trigger); err != nil {
+			log.Error().
+				Err(err).
+				Str("name", name).
+				Msg("Failed watching calendar")
+		} else {
+			if current != nil {
+				receiver.stop(ctx, name, watcher, *current)
+			}
+			current = &channel
+			next = time.Until(channel.Expiration) - renewBefore
+			if next < time.Minute {
+				next = time.Minute
+			}
+		}
+
+		timer := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (receiver *Receiver) renew(ctx context.Context, watcher source.Watcher, trigger chan<- struct{}) (source.Channel, error) {
+	channelId, err := randomId()
+	if err != nil {
+		return source.Channel{}, err
+	}
+
+	// registered first, since the "sync" message can arrive before Watch returns
+	receiver.register(channelId, trigger)
+	channel, err := watcher.Watch(ctx, channelId, receiver.address, receiver.token)
+	if err != nil {
+		receiver.unregister(channelId)
+		return channel, err
+	}
+	return channel, nil
+}
+
+func (receiver *Receiver) stop(ctx context.Context, name string, watcher source.Watcher, channel source.Channel) {
+	receiver.unregister(channel.Id)
+	if err := watcher.Unwatch(ctx, channel); err != nil {
+		log.Warn().
+			Err(err).
+			Str("name", name).
+			Str("channel", channel.Id).
+			Msg("Failed stopping channel")
+	}
+}
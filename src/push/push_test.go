@@ -0,0 +1,187 @@
+package push
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/source"
+)
+
+func newTestReceiver(t *testing.T) *Receiver {
+	t.Helper()
+
+	receiver, err := New(config.Push{
+		Enabled: true,
+		Address: "https://example.com/notifications",
+		Token:   "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return receiver
+}
+
+func notify(t *testing.T, url string, token string, channelId string, state string) int {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("X-Goog-Channel-Token", token)
+	request.Header.Set("X-Goog-Channel-ID", channelId)
+	request.Header.Set("X-Goog-Resource-State", state)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func fired(trigger <-chan struct{}) bool {
+	select {
+	case <-trigger:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		channel string
+		state   string
+		status  int
+		fired   bool
+	}{
+		{"invalid token", "wrong", "channel", "exists", http.StatusForbidden, false},
+		{"missing token", "", "channel", "exists", http.StatusForbidden, false},
+		{"unknown channel", "secret", "other", "exists", http.StatusNotFound, false},
+		{"sync", "secret", "channel", "sync", http.StatusOK, false},
+		{"exists", "secret", "channel", "exists", http.StatusOK, true},
+		{"not exists", "secret", "channel", "not_exists", http.StatusOK, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			receiver := newTestReceiver(t)
+			trigger := make(chan struct{}, 1)
+			receiver.register("channel", trigger)
+
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			if status := notify(t, server.URL, test.token, test.channel, test.state); status != test.status {
+				t.Errorf("status = %d, want %d", status, test.status)
+			}
+			if got := fired(trigger); got != test.fired {
+				t.Errorf("fired = %t, want %t", got, test.fired)
+			}
+		})
+	}
+}
+
+func TestServeHTTPMethod(t *testing.T) {
+	server := httptest.NewServer(newTestReceiver(t))
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestServeHTTPPendingTrigger(t *testing.T) {
+	receiver := newTestReceiver(t)
+	trigger := make(chan struct{}, 1)
+	receiver.register("channel", trigger)
+
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// a second change while a refresh is pending must not block the handler
+	for i := 0; i < 2; i++ {
+		if status := notify(t, server.URL, "secret", "channel", "exists"); status != http.StatusOK {
+			t.Fatalf("status = %d, want %d", status, http.StatusOK)
+		}
+	}
+	if !fired(trigger) {
+		t.Error("trigger did not fire")
+	}
+}
+
+type fakeWatcher struct {
+	mutex   sync.Mutex
+	watched chan source.Channel
+	stopped []string
+}
+
+func (watcher *fakeWatcher) Watch(ctx context.Context, channelId string, address string, token string) (source.Channel, error) {
+	channel := source.Channel{
+		Id:         channelId,
+		ResourceId: "resource",
+		Expiration: time.Now().Add(24 * time.Hour),
+	}
+	watcher.watched <- channel
+	return channel, nil
+}
+
+func (watcher *fakeWatcher) Unwatch(ctx context.Context, channel source.Channel) error {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	watcher.stopped = append(watcher.stopped, channel.Id)
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	receiver := newTestReceiver(t)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	watcher := &fakeWatcher{watched: make(chan source.Channel, 1)}
+	trigger := make(chan struct{}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		receiver.Watch(ctx, "test", watcher, trigger)
+		close(done)
+	}()
+
+	channel := <-watcher.watched
+	if status := notify(t, server.URL, "secret", channel.Id, "exists"); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if !fired(trigger) {
+		t.Error("trigger did not fire")
+	}
+
+	cancel()
+	<-done
+
+	watcher.mutex.Lock()
+	stopped := watcher.stopped
+	watcher.mutex.Unlock()
+	if len(stopped) != 1 || stopped[0] != channel.Id {
+		t.Errorf("stopped = %v, want [%s]", stopped, channel.Id)
+	}
+
+	// the channel is forgotten once stopped
+	if status := notify(t, server.URL, "secret", channel.Id, "exists"); status != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", status, http.StatusNotFound)
+	}
+}
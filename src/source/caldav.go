@@ -0,0 +1,103 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/rs/zerolog/log"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+type caldavSource struct {
+	client *caldav.Client
+	path   string
+}
+
+type bearerHTTPClient struct {
+	token string
+}
+
+func (client bearerHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+client.token)
+	return http.DefaultClient.Do(req)
+}
+
+func newCaldavSource(conf config.Source) (*caldavSource, error) {
+	if conf.Url == "" {
+		return nil, errors.New("caldav source needs the url of the calendar collection")
+	}
+
+	calendarUrl, err := url.Parse(conf.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	var httpClient webdav.HTTPClient = http.DefaultClient
+	switch {
+	case conf.Token != "":
+		httpClient = bearerHTTPClient{
+			token: conf.Token,
+		}
+	case conf.Username != "":
+		httpClient = webdav.HTTPClientWithBasicAuth(http.DefaultClient, conf.Username, conf.Password)
+	}
+
+	endpoint := url.URL{
+		Scheme: calendarUrl.Scheme,
+		Host:   calendarUrl.Host,
+	}
+	client, err := caldav.NewClient(httpClient, endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return &caldavSource{
+		client: client,
+		path:   calendarUrl.Path,
+	}, nil
+}
+
+func (source *caldavSource) Events(ctx context.Context, start time.Time, end time.Time) ([]Event, error) {
+	log.Trace().
+		Str("path", source.path).
+		Msg("Querying CalDAV calendar")
+
+	objects, err := source.client.QueryCalendar(ctx, source.path, &caldav.CalendarQuery{
+		CompRequest: caldav.CalendarCompRequest{
+			Name: "VCALENDAR",
+			Comps: []caldav.CalendarCompRequest{{
+				Name:     "VEVENT",
+				AllProps: true,
+			}},
+		},
+		CompFilter: caldav.CompFilter{
+			Name: "VCALENDAR",
+			Comps: []caldav.CompFilter{{
+				Name:  "VEVENT",
+				Start: start,
+				End:   end,
+			}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every object holds a single event together with its overridden occurrences
+	events := make([]Event, 0, len(objects))
+	for _, object := range objects {
+		if object.Data == nil {
+			continue
+		}
+		events = append(events, expandEvents(object.Data, start, end)...)
+	}
+
+	sortEvents(events)
+	return events, nil
+}
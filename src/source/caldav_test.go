@@ -0,0 +1,198 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+const caldavEvent = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//EN
+BEGIN:VEVENT
+UID:math@test
+DTSTAMP:20240101T000000Z
+DTSTART:20240108T090000Z
+DTEND:20240108T103000Z
+SUMMARY:Math
+LOCATION:Room 5
+DESCRIPTION:Bring a calculator
+END:VEVENT
+END:VCALENDAR
+`
+
+const caldavAllDay = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//EN
+BEGIN:VEVENT
+UID:trip@test
+DTSTAMP:20240101T000000Z
+DTSTART;VALUE=DATE:20240110
+DTEND;VALUE=DATE:20240112
+SUMMARY:Trip
+END:VEVENT
+END:VCALENDAR
+`
+
+func caldavResponse(objects ...string) string {
+	builder := strings.Builder{}
+	builder.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+	for index, object := range objects {
+		fmt.Fprintf(&builder, `
+<d:response>
+<d:href>/calendars/test/%d.ics</d:href>
+<d:propstat>
+<d:prop>
+<d:getetag>"%d"</d:getetag>
+<c:calendar-data>%s</c:calendar-data>
+</d:prop>
+<d:status>HTTP/1.1 200 OK</d:status>
+</d:propstat>
+</d:response>`, index, index, object)
+	}
+	builder.WriteString("\n</d:multistatus>")
+	return builder.String()
+}
+
+// newCaldavServer answers REPORT queries on /calendars/test/ and records what it was asked
+func newCaldavServer(t *testing.T, authorization string, objects ...string) (*httptest.Server, *string) {
+	t.Helper()
+
+	query := new(string)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != authorization {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if request.Method != "REPORT" || request.URL.Path != "/calendars/test/" {
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		*query = string(body)
+
+		writer.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		writer.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(writer, caldavResponse(objects...))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, query
+}
+
+var (
+	caldavStart = time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	caldavEnd   = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+)
+
+func TestCaldavTimeRange(t *testing.T) {
+	server, query := newCaldavServer(t, "")
+
+	source, err := newCaldavSource(config.Source{Url: server.URL + "/calendars/test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Events(context.Background(), caldavStart, caldavEnd); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`name="VEVENT"`, `start="20240108T000000Z"`, `end="20240115T000000Z"`} {
+		if !strings.Contains(*query, want) {
+			t.Errorf("query is missing %s:\n%s", want, *query)
+		}
+	}
+}
+
+func TestCaldavAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		conf          config.Source
+		authorization string
+	}{
+		{"basic", config.Source{Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz"},
+		{"bearer", config.Source{Token: "token"}, "Bearer token"},
+		{"bearer over basic", config.Source{Token: "token", Username: "user", Password: "pass"}, "Bearer token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _ := newCaldavServer(t, test.authorization)
+
+			conf := test.conf
+			conf.Url = server.URL + "/calendars/test/"
+			source, err := newCaldavSource(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := source.Events(context.Background(), caldavStart, caldavEnd); err != nil {
+				t.Errorf("request was not authorized: %v", err)
+			}
+		})
+	}
+}
+
+func TestCaldavEvents(t *testing.T) {
+	server, _ := newCaldavServer(t, "", caldavAllDay, caldavEvent)
+
+	source, err := newCaldavSource(config.Source{Url: server.URL + "/calendars/test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := source.Events(context.Background(), caldavStart, caldavEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+
+	math := events[0]
+	if math.Summary != "Math" || math.Location != "Room 5" || math.Description != "Bring a calculator" || math.AllDay {
+		t.Errorf("unexpected event %+v", math)
+	}
+	if !math.Start.Equal(time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)) || !math.End.Equal(time.Date(2024, 1, 8, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected times %s - %s", math.Start, math.End)
+	}
+
+	trip := events[1]
+	if trip.Summary != "Trip" || !trip.AllDay {
+		t.Errorf("unexpected event %+v", trip)
+	}
+	if days := trip.End.Sub(trip.Start); days != 48*time.Hour {
+		t.Errorf("all day event lasts %s, want 48h", days)
+	}
+}
+
+func TestCaldavLocalTime(t *testing.T) {
+	setLocal(t, "Asia/Tokyo")
+	server, _ := newCaldavServer(t, "", caldavEvent)
+
+	source, err := newCaldavSource(config.Source{Url: server.URL + "/calendars/test/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := source.Events(context.Background(), caldavStart, caldavEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	if got := events[0].Start.Format("Mon 15:04"); got != "Mon 18:00" {
+		t.Errorf("start = %s, want Mon 18:00", got)
+	}
+}
@@ -0,0 +1,285 @@
+package source
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/store"
+)
+
+const defaultRedirectUrl = "http://127.0.0.1:8085"
+
+// oauthMutex makes calendars sharing the OAuth client wait for a single authorization
+var oauthMutex sync.Mutex
+
+type googleSource struct {
+	calendarId string
+	auth       string
+	conf       config.Google
+	st         store.Store
+	// mutex guards service, the watcher and the calendar loop connect at the same time
+	mutex   sync.Mutex
+	service *calendar.Service
+	// changed holds the days touched by the last sync, only the calendar loop reads and writes it
+	changed []string
+}
+
+func newGoogleSource(conf config.Source, google config.Google, st store.Store) *googleSource {
+	return &googleSource{
+		calendarId: conf.Id,
+		auth:       conf.Auth,
+		conf:       google,
+		st:         st,
+	}
+}
+
+// connect keeps the service only once authentication succeeds, otherwise it's retried on the next check
+func (source *googleSource) connect(ctx context.Context) error {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	if source.service != nil {
+		return nil
+	}
+
+	service, err := newGoogleService(ctx, source.auth, source.conf, source.st)
+	if err != nil {
+		return err
+	}
+	source.service = service
+	return nil
+}
+
+func (source *googleSource) Changed() []string {
+	return source.changed
+}
+
+func (source *googleSource) Events(ctx context.Context, start time.Time, end time.Time) ([]Event, error) {
+	if err := source.connect(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Trace().
+		Str("calendar", source.calendarId).
+		Msg("Getting calendar via API")
+
+	state, err := source.sync(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(state.Events))
+	for _, event := range state.Events {
+		if event.Start.Before(end) && (event.End.After(start) || (event.End.Equal(event.Start) && !event.Start.Before(start))) {
+			events = append(events, event)
+		}
+	}
+	sortEvents(events)
+
+	log.Debug().Msg("Decoded API response")
+	return events, nil
+}
+
+func newGoogleEvent(item *calendar.Event) (Event, error) {
+	event := Event{
+		Summary:     item.Summary,
+		Location:    item.Location,
+		Description: item.Description,
+		ColorId:     item.ColorId,
+	}
+
+	if item.Start == nil || item.End == nil {
+		return event, errors.New("event has no start or end")
+	}
+
+	// all day events only have a date, and their end date is exclusive
+	if item.Start.DateTime == "" {
+		start, err := time.ParseInLocation(time.DateOnly, item.Start.Date, time.Local)
+		if err != nil {
+			return event, err
+		}
+		end, err := time.ParseInLocation(time.DateOnly, item.End.Date, time.Local)
+		if err != nil {
+			end = start.AddDate(0, 0, 1)
+		}
+
+		event.Start = start
+		event.End = end
+		event.AllDay = true
+		return event, nil
+	}
+
+	start, err := time.Parse(time.RFC3339, item.Start.DateTime)
+	if err != nil {
+		return event, err
+	}
+	end, err := time.Parse(time.RFC3339, item.End.DateTime)
+	if err != nil {
+		return event, err
+	}
+
+	event.Start = start
+	event.End = end
+	return event, nil
+}
+
+func newGoogleService(ctx context.Context, auth string, conf config.Google, st store.Store) (*calendar.Service, error) {
+	switch auth {
+	case "", "apikey", "api_key", "token":
+		return calendar.NewService(ctx, option.WithAPIKey(conf.Token))
+	case "service_account":
+		tokenSource, err := serviceAccountTokenSource(ctx, conf.ServiceAccount)
+		if err != nil {
+			return nil, err
+		}
+		return calendar.NewService(ctx, option.WithTokenSource(tokenSource))
+	case "oauth":
+		tokenSource, err := oauthTokenSource(ctx, conf.OAuth, st)
+		if err != nil {
+			return nil, err
+		}
+		return calendar.NewService(ctx, option.WithTokenSource(tokenSource))
+	default:
+		return nil, fmt.Errorf("unknown google auth %s", auth)
+	}
+}
+
+func serviceAccountTokenSource(ctx context.Context, conf config.ServiceAccount) (oauth2.TokenSource, error) {
+	data := []byte(conf.Json)
+	if conf.File != "" {
+		fileData, err := os.ReadFile(conf.File)
+		if err != nil {
+			return nil, err
+		}
+		data = fileData
+	}
+	if len(data) == 0 {
+		return nil, errors.New("service account key is missing")
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON(data, calendar.CalendarReadonlyScope)
+	if err != nil {
+		return nil, err
+	}
+
+	// domain-wide delegation impersonates a user of the workspace
+	jwtConfig.Subject = conf.Subject
+
+	return jwtConfig.TokenSource(ctx), nil
+}
+
+func oauthTokenSource(ctx context.Context, conf config.OAuth, st store.Store) (oauth2.TokenSource, error) {
+	oauthConfig := &oauth2.Config{
+		ClientID:     conf.ClientId,
+		ClientSecret: conf.ClientSecret,
+		Endpoint:     google.Endpoint,
+		Scopes:       []string{calendar.CalendarReadonlyScope},
+	}
+
+	if conf.CredentialsFile != "" {
+		data, err := os.ReadFile(conf.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		if oauthConfig, err = google.ConfigFromJSON(data, calendar.CalendarReadonlyScope); err != nil {
+			return nil, err
+		}
+	}
+	if oauthConfig.ClientID == "" {
+		return nil, errors.New("oauth client id is missing")
+	}
+
+	oauthConfig.RedirectURL = conf.RedirectUrl
+	if oauthConfig.RedirectURL == "" {
+		oauthConfig.RedirectURL = defaultRedirectUrl
+	}
+
+	if conf.RefreshToken != "" {
+		return oauthConfig.TokenSource(ctx, &oauth2.Token{
+			RefreshToken: conf.RefreshToken,
+		}), nil
+	}
+
+	oauthMutex.Lock()
+	defer oauthMutex.Unlock()
+
+	tokenKey := store.Key("google.oauth", oauthConfig.ClientID)
+	token := &oauth2.Token{}
+	if found, err := st.Get(tokenKey, token); err != nil {
+		return nil, err
+	} else if !found {
+		if token, err = oauthAuthorize(ctx, oauthConfig); err != nil {
+			return nil, err
+		}
+		if err := st.Set(tokenKey, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return oauthConfig.TokenSource(ctx, token), nil
+}
+
+// oauthAuthorize runs the installed app flow, waiting on the redirect URL for the user to grant access
+func oauthAuthorize(ctx context.Context, oauthConfig *oauth2.Config) (*oauth2.Token, error) {
+	redirectUrl, err := url.Parse(oauthConfig.RedirectURL)
+	if err != nil {
+		return nil, err
+	}
+
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		return nil, err
+	}
+	state := hex.EncodeToString(stateBytes)
+
+	listener, err := net.Listen("tcp", redirectUrl.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(chan string, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("state") != state || r.URL.Query().Get("code") == "" {
+				http.Error(w, "Invalid authorization response", http.StatusBadRequest)
+				return
+			}
+
+			fmt.Fprintln(w, "Smerac is authorized, you can close this window.")
+			select {
+			case codes <- r.URL.Query().Get("code"):
+			default:
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	log.Warn().
+		Str("url", oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)).
+		Str("redirect", oauthConfig.RedirectURL).
+		Msg("Open the URL to authorize access to Google Calendar")
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case code := <-codes:
+		return oauthConfig.Exchange(ctx, code)
+	}
+}
@@ -0,0 +1,318 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/rs/zerolog/log"
+	"github.com/teambition/rrule-go"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+type icsSource struct {
+	url  string
+	file string
+}
+
+func newIcsSource(conf config.Source) (*icsSource, error) {
+	if conf.Url == "" && conf.File == "" {
+		return nil, errors.New("ics source needs an url or a file")
+	}
+
+	// webcal is just a hint for calendar apps to subscribe
+	url := conf.Url
+	if strings.HasPrefix(url, "webcal://") {
+		url = "https://" + strings.TrimPrefix(url, "webcal://")
+	}
+
+	return &icsSource{
+		url:  url,
+		file: conf.File,
+	}, nil
+}
+
+func (source *icsSource) open(ctx context.Context) (io.ReadCloser, error) {
+	if source.file != "" {
+		return os.Open(source.file)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.url, nil)
+	if err != nil {
+		return nil, redact(err)
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, redact(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading calendar failed with status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// redact drops the URL from request errors, private feed URLs are secrets of their own
+func redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("downloading calendar failed: %w", urlErr.Err)
+	}
+	return err
+}
+
+func (source *icsSource) Events(ctx context.Context, start time.Time, end time.Time) ([]Event, error) {
+	log.Trace().
+		Str("file", source.file).
+		Msg("Reading ICS calendar")
+
+	reader, err := source.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	calendar, err := ical.NewDecoder(reader).Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	return expandEvents(calendar, start, end), nil
+}
+
+// icsTimes parses a date or date-time property, which can hold a comma separated list of values
+func icsTimes(prop *ical.Prop, zones icsZones) ([]time.Time, bool, error) {
+	location := time.Local
+	if tzid := prop.Params.Get(ical.PropTimezoneID); tzid != "" {
+		location = zones.location(tzid)
+	}
+
+	values := strings.Split(prop.Value, ",")
+	allDay := prop.ValueType() == ical.ValueDate || len(values[0]) == len("20060102")
+
+	times := make([]time.Time, 0, len(values))
+	for _, value := range values {
+		var moment time.Time
+		var err error
+		switch {
+		case allDay:
+			moment, err = time.ParseInLocation("20060102", value, time.Local)
+		case strings.HasSuffix(value, "Z"):
+			moment, err = time.ParseInLocation("20060102T150405Z", value, time.UTC)
+		default:
+			moment, err = time.ParseInLocation("20060102T150405", value, location)
+		}
+		if err != nil {
+			return nil, allDay, err
+		}
+		times = append(times, moment)
+	}
+
+	return times, allDay, nil
+}
+
+// icsText is lenient with unescaped commas, which a lot of feeds have in their summaries
+func icsText(component *ical.Component, name string) string {
+	prop := component.Props.Get(name)
+	if prop == nil {
+		return ""
+	}
+
+	texts, err := prop.TextList()
+	if err != nil {
+		return prop.Value
+	}
+
+	return strings.Join(texts, ",")
+}
+
+func newIcsEvent(icalEvent ical.Event, zones icsZones) (Event, error) {
+	event := Event{
+		Summary:     icsText(icalEvent.Component, ical.PropSummary),
+		Location:    icsText(icalEvent.Component, ical.PropLocation),
+		Description: icsText(icalEvent.Component, ical.PropDescription),
+		ColorId:     icsText(icalEvent.Component, "COLOR"),
+	}
+
+	startProp := icalEvent.Props.Get(ical.PropDateTimeStart)
+	if startProp == nil {
+		return event, errors.New("event has no start")
+	}
+	starts, allDay, err := icsTimes(startProp, zones)
+	if err != nil {
+		return event, err
+	}
+	event.Start = starts[0]
+	event.AllDay = allDay
+
+	switch {
+	case icalEvent.Props.Get(ical.PropDateTimeEnd) != nil:
+		ends, _, err := icsTimes(icalEvent.Props.Get(ical.PropDateTimeEnd), zones)
+		if err != nil {
+			return event, err
+		}
+		event.End = ends[0]
+	case icalEvent.Props.Get(ical.PropDuration) != nil:
+		duration, err := icalEvent.Props.Get(ical.PropDuration).Duration()
+		if err != nil {
+			return event, err
+		}
+		event.End = event.Start.Add(duration)
+	case allDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+
+	return event, nil
+}
+
+// recurrences returns the starts of every occurrence of a recurring event that can overlap with the window
+func recurrences(icalEvent ical.Event, zones icsZones, event Event, start time.Time, end time.Time) ([]time.Time, error) {
+	set := rrule.Set{}
+	set.DTStart(event.Start)
+
+	roption, err := icalEvent.Props.RecurrenceRule()
+	if err != nil {
+		return nil, err
+	}
+	if roption != nil {
+		rule, err := rrule.NewRRule(*roption)
+		if err != nil {
+			return nil, err
+		}
+		set.RRule(rule)
+	} else {
+		set.RDate(event.Start)
+	}
+
+	for _, prop := range icalEvent.Props.Values(ical.PropRecurrenceDates) {
+		rdates, _, err := icsTimes(&prop, zones)
+		if err != nil {
+			return nil, err
+		}
+		for _, rdate := range rdates {
+			set.RDate(rdate)
+		}
+	}
+	for _, prop := range icalEvent.Props.Values(ical.PropExceptionDates) {
+		exdates, _, err := icsTimes(&prop, zones)
+		if err != nil {
+			return nil, err
+		}
+		for _, exdate := range exdates {
+			set.ExDate(exdate)
+		}
+	}
+
+	return set.Between(start.Add(-event.End.Sub(event.Start)).Add(-24*time.Hour), end, true), nil
+}
+
+// expandEvents turns VEVENTs into single occurrences inside of the window, applying RRULE, RDATE, EXDATE and RECURRENCE-ID
+func expandEvents(calendar *ical.Calendar, start time.Time, end time.Time) []Event {
+	icalEvents := calendar.Events()
+	zones := newIcsZones(calendar)
+
+	// occurrences which were moved or changed are separate events with the same UID
+	overridden := make(map[string][]time.Time)
+	for _, icalEvent := range icalEvents {
+		if prop := icalEvent.Props.Get(ical.PropRecurrenceID); prop != nil {
+			uid := icsText(icalEvent.Component, ical.PropUID)
+			recurrenceIds, _, err := icsTimes(prop, zones)
+			if err != nil {
+				log.Error().
+					Err(err).
+					Str("uid", uid).
+					Msg("Failed parsing recurrence id")
+				continue
+			}
+			overridden[uid] = append(overridden[uid], recurrenceIds...)
+		}
+	}
+
+	events := make([]Event, 0, len(icalEvents))
+	for _, icalEvent := range icalEvents {
+		if status, _ := icalEvent.Status(); status == ical.EventCancelled {
+			continue
+		}
+
+		event, err := newIcsEvent(icalEvent, zones)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("summary", event.Summary).
+				Msg("Failed parsing time")
+			continue
+		}
+
+		starts := []time.Time{event.Start}
+		recurring := icalEvent.Props.Get(ical.PropRecurrenceRule) != nil || icalEvent.Props.Get(ical.PropRecurrenceDates) != nil
+		if recurring && icalEvent.Props.Get(ical.PropRecurrenceID) == nil {
+			if starts, err = recurrences(icalEvent, zones, event, start, end); err != nil {
+				log.Error().
+					Err(err).
+					Str("summary", event.Summary).
+					Msg("Failed expanding recurrence")
+				continue
+			}
+		}
+
+		uid := icsText(icalEvent.Component, ical.PropUID)
+		days := int(event.End.Sub(event.Start).Hours()+12) / 24
+		for _, occurrenceStart := range starts {
+			if icalEvent.Props.Get(ical.PropRecurrenceID) == nil && isOverridden(overridden[uid], occurrenceStart) {
+				continue
+			}
+
+			occurrence := event
+			occurrence.Start = occurrenceStart
+			if event.AllDay {
+				// days keep their length across daylight saving changes
+				occurrence.End = occurrenceStart.AddDate(0, 0, days)
+			} else {
+				occurrence.End = occurrenceStart.Add(event.End.Sub(event.Start))
+			}
+
+			// UTC and foreign times are shown in local time, which also decides their day
+			if !occurrence.AllDay {
+				occurrence.Start = occurrence.Start.In(time.Local)
+				occurrence.End = occurrence.End.In(time.Local)
+			}
+
+			if occurrence.Start.Before(end) && (occurrence.End.After(start) || (occurrence.End.Equal(occurrence.Start) && !occurrence.Start.Before(start))) {
+				events = append(events, occurrence)
+			}
+		}
+	}
+
+	sortEvents(events)
+	return events
+}
+
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+}
+
+func isOverridden(recurrenceIds []time.Time, occurrenceStart time.Time) bool {
+	for _, recurrenceId := range recurrenceIds {
+		if recurrenceId.Equal(occurrenceStart) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,274 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+// outlookFeed names its zones the Windows way and only defines them in VTIMEZONE blocks
+const outlookFeed = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:Microsoft Exchange Server 2010
+BEGIN:VTIMEZONE
+TZID:Central Europe Standard Time
+BEGIN:STANDARD
+DTSTART:16010101T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=-1SU;BYMONTH=10
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:16010101T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=-1SU;BYMONTH=3
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VTIMEZONE
+TZID:AUS Eastern Standard Time
+BEGIN:STANDARD
+DTSTART:16010101T030000
+TZOFFSETFROM:+1100
+TZOFFSETTO:+1000
+RRULE:FREQ=YEARLY;BYDAY=SU;BYMONTHDAY=1,2,3,4,5,6,7;BYMONTH=4
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:16010101T020000
+TZOFFSETFROM:+1000
+TZOFFSETTO:+1100
+RRULE:FREQ=YEARLY;BYDAY=SU;BYMONTHDAY=1,2,3,4,5,6,7;BYMONTH=10
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:winter@test
+DTSTAMP:20240101T000000Z
+DTSTART;TZID=Central Europe Standard Time:20240115T090000
+DTEND;TZID=Central Europe Standard Time:20240115T100000
+SUMMARY:Winter
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly@test
+DTSTAMP:20240101T000000Z
+DTSTART;TZID=Central Europe Standard Time:20240321T090000
+DTEND;TZID=Central Europe Standard Time:20240321T100000
+RRULE:FREQ=WEEKLY;COUNT=3
+SUMMARY:Weekly
+END:VEVENT
+BEGIN:VEVENT
+UID:sydney@test
+DTSTAMP:20240101T000000Z
+DTSTART;TZID=AUS Eastern Standard Time:20240115T090000
+DTEND;TZID=AUS Eastern Standard Time:20240115T100000
+SUMMARY:Sydney
+END:VEVENT
+BEGIN:VEVENT
+UID:iana@test
+DTSTAMP:20240101T000000Z
+DTSTART;TZID=America/New_York:20240715T090000
+DTEND;TZID=America/New_York:20240715T100000
+SUMMARY:New York
+END:VEVENT
+END:VCALENDAR
+`
+
+func readFeed(t *testing.T, feed string, start time.Time, end time.Time) map[string][]time.Time {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "feed.ics")
+	if err := os.WriteFile(file, []byte(strings.ReplaceAll(feed, "\n", "\r\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := newIcsSource(config.Source{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := source.Events(context.Background(), start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starts := make(map[string][]time.Time)
+	for _, event := range events {
+		starts[event.Summary] = append(starts[event.Summary], event.Start.UTC())
+	}
+	return starts
+}
+
+func TestIcsVtimezone(t *testing.T) {
+	starts := readFeed(t, outlookFeed, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		summary string
+		want    []time.Time
+	}{
+		{"Winter", []time.Time{time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)}},
+		// daylight saving time starts on the 31st of March, between the second and the third occurrence
+		{"Weekly", []time.Time{
+			time.Date(2024, 3, 21, 8, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 28, 8, 0, 0, 0, time.UTC),
+			time.Date(2024, 4, 4, 7, 0, 0, 0, time.UTC),
+		}},
+		// January is summer there
+		{"Sydney", []time.Time{time.Date(2024, 1, 14, 22, 0, 0, 0, time.UTC)}},
+		{"New York", []time.Time{time.Date(2024, 7, 15, 13, 0, 0, 0, time.UTC)}},
+	}
+
+	for _, test := range tests {
+		got := starts[test.summary]
+		if len(got) != len(test.want) {
+			t.Errorf("%s starts = %v, want %v", test.summary, got, test.want)
+			continue
+		}
+		for index := range got {
+			if !got[index].Equal(test.want[index]) {
+				t.Errorf("%s starts = %v, want %v", test.summary, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestIcsUnknownTimezone(t *testing.T) {
+	zones := newIcsZones(ical.NewCalendar())
+	if location := zones.location("Nowhere Standard Time"); location != time.Local {
+		t.Errorf("location = %s, want the local one", location)
+	}
+}
+
+func TestPosixRule(t *testing.T) {
+	tests := []struct {
+		rrule string
+		start string
+		want  string
+	}{
+		{"FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3", "16010101T020000", "M3.5.0/02:00:00"},
+		{"FREQ=YEARLY;BYDAY=2SU;BYMONTH=3", "20070311T020000", "M3.2.0/02:00:00"},
+		{"FREQ=YEARLY;BYDAY=SU;BYMONTHDAY=8,9,10,11,12,13,14;BYMONTH=3", "16010101T020000", "M3.2.0/02:00:00"},
+		{"FREQ=YEARLY;BYDAY=-1FR;BYMONTH=10", "16010101T010000", "M10.5.5/01:00:00"},
+	}
+
+	for _, test := range tests {
+		observance := ical.NewComponent(ical.CompTimezoneDaylight)
+		for name, value := range map[string]string{ical.PropRecurrenceRule: test.rrule, ical.PropDateTimeStart: test.start} {
+			prop := ical.NewProp(name)
+			prop.Value = value
+			observance.Props.Set(prop)
+		}
+
+		got, err := posixRule(observance)
+		if err != nil {
+			t.Errorf("%s: %v", test.rrule, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s = %s, want %s", test.rrule, got, test.want)
+		}
+	}
+}
+
+// setLocal swaps the local timezone for the length of the test
+func setLocal(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := time.Local
+	time.Local = location
+	t.Cleanup(func() {
+		time.Local = local
+	})
+	return location
+}
+
+const utcFeed = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Google Inc//Google Calendar 70.9054//EN
+BEGIN:VEVENT
+UID:late@test
+DTSTAMP:20240101T000000Z
+DTSTART:20240108T233000Z
+DTEND:20240109T003000Z
+SUMMARY:Late
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly@test
+DTSTAMP:20240101T000000Z
+DTSTART:20240108T080000Z
+DTEND:20240108T090000Z
+RRULE:FREQ=WEEKLY;COUNT=2
+SUMMARY:Weekly
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestIcsLocalTime(t *testing.T) {
+	belgrade := setLocal(t, "Europe/Belgrade")
+
+	file := filepath.Join(t.TempDir(), "feed.ics")
+	if err := os.WriteFile(file, []byte(strings.ReplaceAll(utcFeed, "\n", "\r\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	source, err := newIcsSource(config.Source{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := source.Events(context.Background(), time.Date(2024, 1, 8, 0, 0, 0, 0, belgrade), time.Date(2024, 1, 22, 0, 0, 0, 0, belgrade))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		summary string
+		weekday time.Weekday
+		start   string
+	}{
+		// half past eleven UTC is already Tuesday in Belgrade
+		{"Weekly", time.Monday, "09:00"},
+		{"Late", time.Tuesday, "00:30"},
+		{"Weekly", time.Monday, "09:00"},
+	}
+	if len(events) != len(tests) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(tests), events)
+	}
+	for index, test := range tests {
+		event := events[index]
+		if event.Summary != test.summary || event.Start.Weekday() != test.weekday || event.Start.Format("15:04") != test.start {
+			t.Errorf("event %d = %s on %s at %s, want %s on %s at %s", index, event.Summary, event.Start.Weekday(), event.Start.Format("15:04"), test.summary, test.weekday, test.start)
+		}
+		if event.Start.Location() != belgrade {
+			t.Errorf("%s is in %s, want the local timezone", event.Summary, event.Start.Location())
+		}
+	}
+}
+
+func TestIcsRedactsUrl(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	feedUrl := server.URL + "/calendar/ical/private-0123456789abcdef/basic.ics"
+	// nothing listens anymore, so the request fails before it gets a response
+	server.Close()
+
+	source, err := newIcsSource(config.Source{Url: feedUrl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = source.Events(context.Background(), time.Now(), time.Now().AddDate(0, 0, 7))
+	if err == nil {
+		t.Fatal("reading a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), "private-0123456789abcdef") {
+		t.Errorf("error leaks the feed url: %v", err)
+	}
+}
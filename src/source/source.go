@@ -0,0 +1,33 @@
+package source
+
+import (
+	"fmt"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+	"github.com/aleksasiriski/smerac-go/src/store"
+)
+
+func New(conf config.Source, google config.Google, st store.Store) (Source, error) {
+	switch conf.Type {
+	case "", "google":
+		return newGoogleSource(conf, google, st), nil
+	case "ics", "ical":
+		return newIcsSource(conf)
+	case "caldav":
+		return newCaldavSource(conf)
+	default:
+		return nil, fmt.Errorf("unknown source type %s", conf.Type)
+	}
+}
+
+// Id identifies the calendar behind a source, Google calendars keep their plain id so older states stay valid
+func Id(conf config.Source) string {
+	switch {
+	case conf.Id != "":
+		return conf.Id
+	case conf.Url != "":
+		return conf.Url
+	default:
+		return conf.File
+	}
+}
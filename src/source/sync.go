@@ -0,0 +1,148 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+
+	"github.com/aleksasiriski/smerac-go/src/store"
+)
+
+// syncHorizon is how far ahead a full sync reaches, so the moving window doesn't need one on every check
+const syncHorizon = 28 * 24 * time.Hour
+
+type googleSync struct {
+	Token  string           `json:"token"`
+	Start  time.Time        `json:"start"`
+	End    time.Time        `json:"end"`
+	Events map[string]Event `json:"events"`
+}
+
+func (source *googleSource) syncKey() string {
+	return store.Key("google.sync", source.calendarId)
+}
+
+// sync brings the cached events up to date, incrementally when the stored sync token is still valid
+func (source *googleSource) sync(ctx context.Context, start time.Time, end time.Time) (googleSync, error) {
+	// until an incremental sync says otherwise, any day may have changed
+	source.changed = nil
+
+	state := googleSync{}
+	if _, err := source.st.Get(source.syncKey(), &state); err != nil {
+		return state, err
+	}
+
+	if state.Token != "" && !state.Start.After(start) && !state.End.Before(end) {
+		err := source.syncIncremental(ctx, &state)
+		if err == nil {
+			return state, source.st.Set(source.syncKey(), state)
+		}
+
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusGone {
+			return state, err
+		}
+
+		log.Debug().
+			Str("calendar", source.calendarId).
+			Msg("Sync token expired, doing a full sync")
+	}
+
+	state = googleSync{
+		Start:  start,
+		End:    start.Add(syncHorizon),
+		Events: make(map[string]Event),
+	}
+	if state.End.Before(end) {
+		state.End = end
+	}
+
+	if err := source.service.Events.List(source.calendarId).ShowDeleted(false).SingleEvents(true).
+		TimeMin(state.Start.Format(time.RFC3339)).TimeMax(state.End.Format(time.RFC3339)).
+		Pages(ctx, func(page *calendar.Events) error {
+			state.apply(page.Items)
+			state.Token = page.NextSyncToken
+			return nil
+		}); err != nil {
+		return state, err
+	}
+
+	log.Debug().
+		Str("calendar", source.calendarId).
+		Int("events", len(state.Events)).
+		Msg("Fully synced calendar")
+	return state, source.st.Set(source.syncKey(), state)
+}
+
+func (source *googleSource) syncIncremental(ctx context.Context, state *googleSync) error {
+	changes := make([]*calendar.Event, 0)
+	token := state.Token
+
+	if err := source.service.Events.List(source.calendarId).SingleEvents(true).SyncToken(token).
+		Pages(ctx, func(page *calendar.Events) error {
+			changes = append(changes, page.Items...)
+			token = page.NextSyncToken
+			return nil
+		}); err != nil {
+		return err
+	}
+
+	affected := state.apply(changes)
+	state.Token = token
+	source.changed = affected
+
+	if len(changes) > 0 {
+		log.Debug().
+			Str("calendar", source.calendarId).
+			Int("changes", len(changes)).
+			Strs("days", affected).
+			Msg("Incrementally synced calendar")
+	} else {
+		log.Trace().
+			Str("calendar", source.calendarId).
+			Msg("Calendar has no changes")
+	}
+
+	return nil
+}
+
+// apply updates the cached events and returns the local days touched by the changes, both before and after them
+func (state *googleSync) apply(items []*calendar.Event) []string {
+	affected := make([]string, 0)
+	touch := func(event Event) {
+		start := event.Start.In(time.Local)
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+		for ; !day.After(event.Start) || day.Before(event.End); day = day.AddDate(0, 0, 1) {
+			affected = append(affected, day.Format(time.DateOnly))
+		}
+	}
+
+	for _, item := range items {
+		if old, found := state.Events[item.Id]; found {
+			touch(old)
+		}
+
+		if item.Status == "cancelled" {
+			delete(state.Events, item.Id)
+			continue
+		}
+
+		event, err := newGoogleEvent(item)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("summary", item.Summary).
+				Msg("Failed parsing time")
+			continue
+		}
+		state.Events[item.Id] = event
+		touch(event)
+	}
+
+	return affected
+}
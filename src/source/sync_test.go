@@ -0,0 +1,68 @@
+package source
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestApplyAffectedDays(t *testing.T) {
+	setLocal(t, "Europe/Belgrade")
+
+	state := googleSync{
+		Events: map[string]Event{
+			"moved": {
+				Summary: "Moved",
+				Start:   time.Date(2024, 1, 8, 9, 0, 0, 0, time.Local),
+				End:     time.Date(2024, 1, 8, 10, 0, 0, 0, time.Local),
+			},
+			"cancelled": {
+				Summary: "Cancelled",
+				Start:   time.Date(2024, 1, 12, 9, 0, 0, 0, time.Local),
+				End:     time.Date(2024, 1, 12, 10, 0, 0, 0, time.Local),
+			},
+		},
+	}
+
+	affected := state.apply([]*calendar.Event{
+		{
+			Id:      "moved",
+			Summary: "Moved",
+			Start:   &calendar.EventDateTime{DateTime: "2024-01-10T09:00:00+01:00"},
+			End:     &calendar.EventDateTime{DateTime: "2024-01-10T10:00:00+01:00"},
+		},
+		{
+			Id:     "cancelled",
+			Status: "cancelled",
+		},
+		{
+			// late on Sunday in UTC is already Monday in Belgrade
+			Id:      "late",
+			Summary: "Late",
+			Start:   &calendar.EventDateTime{DateTime: "2024-01-14T23:30:00Z"},
+			End:     &calendar.EventDateTime{DateTime: "2024-01-15T00:30:00Z"},
+		},
+		{
+			Id:      "trip",
+			Summary: "Trip",
+			Start:   &calendar.EventDateTime{Date: "2024-01-20"},
+			End:     &calendar.EventDateTime{Date: "2024-01-22"},
+		},
+	})
+
+	slices.Sort(affected)
+	affected = slices.Compact(affected)
+	want := []string{"2024-01-08", "2024-01-10", "2024-01-12", "2024-01-15", "2024-01-20", "2024-01-21"}
+	if !slices.Equal(affected, want) {
+		t.Errorf("affected = %v, want %v", affected, want)
+	}
+
+	if _, found := state.Events["cancelled"]; found {
+		t.Error("cancelled event is still cached")
+	}
+	if len(state.Events) != 3 {
+		t.Errorf("cached %d events, want 3", len(state.Events))
+	}
+}
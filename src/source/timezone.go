@@ -0,0 +1,232 @@
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/rs/zerolog/log"
+	"github.com/teambition/rrule-go"
+)
+
+// icsZones resolves the TZIDs of a feed, by their IANA names or else by the feed's own VTIMEZONE definitions
+type icsZones map[string]*time.Location
+
+func newIcsZones(calendar *ical.Calendar) icsZones {
+	zones := make(icsZones)
+	if calendar == nil {
+		return zones
+	}
+
+	for _, child := range calendar.Children {
+		if child.Name != ical.CompTimezone {
+			continue
+		}
+		tzid := icsText(child, ical.PropTimezoneID)
+		if tzid == "" {
+			continue
+		}
+		if _, err := time.LoadLocation(tzid); err == nil {
+			continue
+		}
+
+		// some producers name the IANA zone next to their own TZID
+		if location, err := time.LoadLocation(icsText(child, "X-LIC-LOCATION")); err == nil && location != time.UTC {
+			zones[tzid] = location
+			continue
+		}
+
+		location, err := vtimezoneLocation(tzid, child)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("tzid", tzid).
+				Msg("Failed reading timezone definition")
+			continue
+		}
+		zones[tzid] = location
+	}
+
+	return zones
+}
+
+// location falls back to the local timezone for TZIDs that can't be resolved, warning about each of them once
+func (zones icsZones) location(tzid string) *time.Location {
+	if location, found := zones[tzid]; found {
+		return location
+	}
+
+	location, err := time.LoadLocation(tzid)
+	if err != nil {
+		log.Warn().
+			Str("tzid", tzid).
+			Msg("Unknown timezone, using the local one")
+		location = time.Local
+	}
+	zones[tzid] = location
+	return location
+}
+
+// vtimezoneLocation builds a location from the current STANDARD and DAYLIGHT observances,
+// as a POSIX TZ rule inside of otherwise empty TZif data
+func vtimezoneLocation(tzid string, vtimezone *ical.Component) (*time.Location, error) {
+	var standard, daylight *ical.Component
+	for _, observance := range vtimezone.Children {
+		switch observance.Name {
+		case ical.CompTimezoneStandard:
+			standard = latestObservance(standard, observance)
+		case ical.CompTimezoneDaylight:
+			daylight = latestObservance(daylight, observance)
+		}
+	}
+	if standard == nil {
+		return nil, errors.New("timezone has no standard time")
+	}
+
+	standardOffset, err := utcOffset(standard)
+	if err != nil {
+		return nil, err
+	}
+	tz := posixName(standard, standardOffset) + posixOffset(standardOffset)
+
+	if daylight != nil {
+		daylightOffset, err := utcOffset(daylight)
+		if err != nil {
+			return nil, err
+		}
+		daylightStart, err := posixRule(daylight)
+		if err != nil {
+			return nil, err
+		}
+		daylightEnd, err := posixRule(standard)
+		if err != nil {
+			return nil, err
+		}
+		tz += posixName(daylight, daylightOffset) + posixOffset(daylightOffset) + "," + daylightStart + "," + daylightEnd
+	}
+
+	return time.LoadLocationFromTZData(tzid, tzData(tz, standardOffset, posixName(standard, standardOffset)))
+}
+
+// latestObservance keeps the observance that started last, older ones only matter for past dates
+func latestObservance(current *ical.Component, observance *ical.Component) *ical.Component {
+	if current == nil {
+		return observance
+	}
+	if icsText(observance, ical.PropDateTimeStart) > icsText(current, ical.PropDateTimeStart) {
+		return observance
+	}
+	return current
+}
+
+// utcOffset parses TZOFFSETTO, which is written as +hhmm or +hhmmss
+func utcOffset(observance *ical.Component) (int, error) {
+	value := icsText(observance, ical.PropTimezoneOffsetTo)
+	if len(value) != 5 && len(value) != 7 || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("invalid offset %q", value)
+	}
+
+	offset := 0
+	for index, unit := range []int{3600, 60, 1} {
+		if 1+2*index >= len(value) {
+			break
+		}
+		part, err := strconv.Atoi(value[1+2*index : 3+2*index])
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %q", value)
+		}
+		offset += part * unit
+	}
+
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// posixName quotes the abbreviation, since POSIX only allows bare letters
+func posixName(observance *ical.Component, offset int) string {
+	name := strings.Trim(icsText(observance, ical.PropTimezoneName), "<>")
+	if name == "" {
+		name = strings.ReplaceAll(strings.TrimSuffix(posixOffset(-offset), ":00"), ":", "")
+	}
+	return "<" + name + ">"
+}
+
+// posixOffset is the time to add to get UTC, so its sign is the opposite of TZOFFSETTO
+func posixOffset(offset int) string {
+	sign := "-"
+	if offset <= 0 {
+		sign = "+"
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%02d:%02d:%02d", sign, offset/3600, offset/60%60, offset%60)
+}
+
+// posixRule turns a yearly RRULE such as the last Sunday of March into M3.5.0,
+// with the time of DTSTART which is in the local time before the change
+func posixRule(observance *ical.Component) (string, error) {
+	roption, err := observance.Props.RecurrenceRule()
+	if err != nil {
+		return "", err
+	}
+	if roption == nil || roption.Freq != rrule.YEARLY || len(roption.Bymonth) != 1 || len(roption.Byweekday) != 1 {
+		return "", errors.New("timezone change isn't a yearly rule on a weekday of a month")
+	}
+
+	start, err := time.Parse("20060102T150405", icsText(observance, ical.PropDateTimeStart))
+	if err != nil {
+		return "", err
+	}
+
+	weekday := roption.Byweekday[0]
+	week := weekday.N()
+	switch {
+	case week < 0:
+		// only the last week can be counted from the end
+		week = 5
+	case week == 0 && len(roption.Bymonthday) > 0:
+		// the second Sunday can also be written as a Sunday between the 8th and the 14th
+		first := roption.Bymonthday[0]
+		for _, day := range roption.Bymonthday {
+			first = min(first, day)
+		}
+		week = (first-1)/7 + 1
+	case week == 0:
+		return "", errors.New("timezone change has no week")
+	}
+
+	// rrule counts weekdays from Monday, POSIX from Sunday
+	return fmt.Sprintf("M%d.%d.%d/%s", roption.Bymonth[0], min(week, 5), (weekday.Day()+1)%7, start.Format("15:04:05")), nil
+}
+
+// tzData is version 2 TZif data without any transitions, so the footer rule applies to all times
+func tzData(tz string, offset int, name string) []byte {
+	buffer := new(bytes.Buffer)
+	header := func(zones uint32, chars uint32) {
+		buffer.WriteString("TZif2")
+		buffer.Write(make([]byte, 15))
+		// UTC/local indicators, standard/wall indicators, leap seconds, transitions, zones and abbreviation characters
+		for _, count := range []uint32{0, 0, 0, 0, zones, chars} {
+			binary.Write(buffer, binary.BigEndian, count)
+		}
+	}
+
+	// the version 1 block is empty, it's skipped by version 2 readers
+	header(0, 0)
+
+	abbreviation := strings.Trim(name, "<>") + "\x00"
+	header(1, uint32(len(abbreviation)))
+	binary.Write(buffer, binary.BigEndian, int32(offset))
+	buffer.WriteByte(0)
+	buffer.WriteByte(0)
+	buffer.WriteString(abbreviation)
+
+	buffer.WriteString("\n" + tz + "\n")
+	return buffer.Bytes()
+}
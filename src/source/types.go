@@ -0,0 +1,39 @@
+package source
+
+import (
+	"context"
+	"time"
+)
+
+type Event struct {
+	Summary     string
+	Location    string
+	Description string
+	ColorId     string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+type Source interface {
+	Events(ctx context.Context, start time.Time, end time.Time) ([]Event, error)
+}
+
+// Changer is implemented by sources that know which days their last Events call changed
+type Changer interface {
+	// Changed returns the changed days as local dates in the time.DateOnly layout, or nil when any day may have changed
+	Changed() []string
+}
+
+// Channel is a registered push notification channel
+type Channel struct {
+	Id         string
+	ResourceId string
+	Expiration time.Time
+}
+
+// Watcher is implemented by sources that can push notifications about changes
+type Watcher interface {
+	Watch(ctx context.Context, channelId string, address string, token string) (Channel, error)
+	Unwatch(ctx context.Context, channel Channel) error
+}
@@ -0,0 +1,48 @@
+package source
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/api/calendar/v3"
+)
+
+func (source *googleSource) Watch(ctx context.Context, channelId string, address string, token string) (Channel, error) {
+	if err := source.connect(ctx); err != nil {
+		return Channel{}, err
+	}
+
+	response, err := source.service.Events.Watch(source.calendarId, &calendar.Channel{
+		Id:      channelId,
+		Type:    "web_hook",
+		Address: address,
+		Token:   token,
+	}).Context(ctx).Do()
+	if err != nil {
+		return Channel{}, err
+	}
+
+	channel := Channel{
+		Id:         response.Id,
+		ResourceId: response.ResourceId,
+		Expiration: time.UnixMilli(response.Expiration),
+	}
+	log.Debug().
+		Str("calendar", source.calendarId).
+		Str("channel", channel.Id).
+		Time("expiration", channel.Expiration).
+		Msg("Watching calendar")
+	return channel, nil
+}
+
+func (source *googleSource) Unwatch(ctx context.Context, channel Channel) error {
+	if err := source.connect(ctx); err != nil {
+		return err
+	}
+
+	return source.service.Channels.Stop(&calendar.Channel{
+		Id:         channel.Id,
+		ResourceId: channel.ResourceId,
+	}).Context(ctx).Do()
+}
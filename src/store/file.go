@@ -0,0 +1,93 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type fileStore struct {
+	path   string
+	mutex  sync.Mutex
+	values map[string]json.RawMessage
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	store := &fileStore{
+		path:   path,
+		values: make(map[string]json.RawMessage),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &store.values); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+func (s *fileStore) Get(key string, value any) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, ok := s.values[key]
+	if !ok {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(data, value)
+}
+
+func (s *fileStore) Set(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.values[key] = data
+	return s.save()
+}
+
+func (s *fileStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.values, key)
+	return s.save()
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
+
+// save writes to a temporary file first so a crash never leaves a half written store behind
+func (s *fileStore) save() error {
+	data, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, s.path)
+}
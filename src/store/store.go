@@ -0,0 +1,34 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+func New(storeType string, path string) (Store, error) {
+	switch storeType {
+	case "", "file", "json":
+		return newFileStore(path)
+	case "bolt", "bbolt", "db":
+		return newBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store type %s", storeType)
+	}
+}
+
+// Key hashes its parts so that secrets such as webhook tokens never end up in the store
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func Hash(values ...string) string {
+	hash := sha256.New()
+	for _, value := range values {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
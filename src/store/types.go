@@ -0,0 +1,18 @@
+package store
+
+import "time"
+
+type Store interface {
+	Get(key string, value any) (bool, error)
+	Set(key string, value any) error
+	Delete(key string) error
+	Close() error
+}
+
+type State struct {
+	Hash      string              `json:"hash"`
+	Output    map[string]string   `json:"output"`
+	Messages  map[string][]string `json:"messages,omitempty"`
+	Created   []string            `json:"created,omitempty"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+type EmailMessage struct {
+	// Subject isn't compared between checks, so a subject with the date doesn't resend an unchanged schedule
+	Subject string `json:"-"`
+	Text    string `json:"text"`
+	Html    string `json:"html"`
+}
+
+type emailNotifier struct {
+	conf       config.Smtp
+	from       *mail.Address
+	recipients []string
+	policy     Policy
+}
+
+func newEmailNotifier(conf config.Smtp, recipients []string, policy Policy) (*emailNotifier, error) {
+	if conf.Host == "" || conf.From == "" || len(recipients) == 0 {
+		return nil, errors.New("smtp host, from and recipients are required")
+	}
+
+	from, err := mail.ParseAddress(conf.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
+	for _, recipient := range recipients {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return nil, fmt.Errorf("invalid recipient: %w", err)
+		}
+	}
+
+	switch conf.Security {
+	case "", "starttls":
+		conf.Security = "starttls"
+		if conf.Port == 0 {
+			conf.Port = 587
+		}
+	case "tls":
+		if conf.Port == 0 {
+			conf.Port = 465
+		}
+	case "none":
+		if conf.Port == 0 {
+			conf.Port = 25
+		}
+	default:
+		return nil, fmt.Errorf("unknown smtp security %s", conf.Security)
+	}
+
+	return &emailNotifier{
+		conf:       conf,
+		from:       from,
+		recipients: recipients,
+		policy:     policy,
+	}, nil
+}
+
+func messageId(domain string) (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return "<" + hex.EncodeToString(buffer) + "@" + domain + ">", nil
+}
+
+// compose builds a multipart/alternative message, with the HTML last so it's preferred
+func (notifier *emailNotifier) compose(message EmailMessage) ([]byte, error) {
+	body := new(bytes.Buffer)
+	parts := multipart.NewWriter(body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.Html},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	id, err := messageId(notifier.from.Address[strings.LastIndex(notifier.from.Address, "@")+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	header := new(bytes.Buffer)
+	for _, line := range [][2]string{
+		{"From", notifier.from.String()},
+		{"To", strings.Join(notifier.recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", id},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	} {
+		fmt.Fprintf(header, "%s: %s\r\n", line[0], line[1])
+	}
+	header.WriteString("\r\n")
+
+	return append(header.Bytes(), body.Bytes()...), nil
+}
+
+// dial returns a client ready to send, the returned function has to be called once it's done
+func (notifier *emailNotifier) dial(ctx context.Context) (*smtp.Client, func() bool, error) {
+	address := net.JoinHostPort(notifier.conf.Host, strconv.Itoa(notifier.conf.Port))
+	tlsConfig := &tls.Config{
+		ServerName: notifier.conf.Host,
+	}
+
+	var conn net.Conn
+	var err error
+	if notifier.conf.Security == "tls" {
+		dialer := &tls.Dialer{
+			Config: tlsConfig,
+		}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		dialer := &net.Dialer{}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the SMTP client has no context of its own, closing the connection interrupts it
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+
+	client, err := smtp.NewClient(conn, notifier.conf.Host)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if notifier.conf.Security == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			stop()
+			client.Close()
+			return nil, nil, err
+		}
+	}
+
+	if notifier.conf.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", notifier.conf.Username, notifier.conf.Password, notifier.conf.Host)); err != nil {
+			stop()
+			client.Close()
+			return nil, nil, err
+		}
+	}
+
+	return client, stop, nil
+}
+
+// Send mails the message to every recipient, there's nothing to refer to afterwards
+func (notifier *emailNotifier) Send(ctx context.Context, message any) (string, error) {
+	emailMessage, ok := message.(EmailMessage)
+	if !ok {
+		return "", fmt.Errorf("email can't send %T", message)
+	}
+
+	content, err := notifier.compose(emailMessage)
+	if err != nil {
+		return "", err
+	}
+
+	// temporary SMTP replies and connection errors are retried like HTTP requests
+	return "", notifier.policy.retry(ctx, func(ctx context.Context) error {
+		return notifier.send(ctx, content)
+	})
+}
+
+func (notifier *emailNotifier) send(ctx context.Context, content []byte) error {
+	client, stop, err := notifier.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	defer client.Close()
+
+	if err := client.Mail(notifier.from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range notifier.recipients {
+		address, _ := mail.ParseAddress(recipient)
+		if err := client.Rcpt(address.Address); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(content); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	// the mail is accepted by now, retrying because of a failed goodbye would send it twice
+	client.Quit()
+	return nil
+}
+
+func (notifier *emailNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	return errors.ErrUnsupported
+}
+
+func (notifier *emailNotifier) Delete(ctx context.Context, messageId string) error {
+	return errors.ErrUnsupported
+}
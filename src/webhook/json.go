@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultSignatureHeader = "X-Smerac-Signature"
+
+type JsonWeek struct {
+	// SentAt is set when the week is sent, so it isn't compared between checks
+	SentAt string    `json:"sent_at,omitempty"`
+	Days   []JsonDay `json:"days"`
+}
+
+type JsonDay struct {
+	Key   string     `json:"key"`
+	Name  string     `json:"name"`
+	Items []JsonItem `json:"items"`
+}
+
+type JsonItem struct {
+	Name    string     `json:"name"`
+	Label   string     `json:"label,omitempty"`
+	ColorId string     `json:"color_id,omitempty"`
+	Infos   []JsonInfo `json:"infos"`
+}
+
+type JsonInfo struct {
+	Name        string           `json:"name"`
+	Occurrences []JsonOccurrence `json:"occurrences"`
+}
+
+type JsonOccurrence struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	AllDay bool   `json:"all_day"`
+}
+
+type jsonNotifier struct {
+	url             string
+	header          http.Header
+	secret          []byte
+	signatureHeader string
+	policy          Policy
+}
+
+func newJsonNotifier(webhookUrl string, headers map[string]string, secret string, signatureHeader string, policy Policy) (*jsonNotifier, error) {
+	if webhookUrl == "" {
+		return nil, errors.New("json webhook is required")
+	}
+	if signatureHeader == "" {
+		signatureHeader = defaultSignatureHeader
+	}
+
+	header := http.Header{}
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+
+	return &jsonNotifier{
+		url:             webhookUrl,
+		header:          header,
+		secret:          []byte(secret),
+		signatureHeader: signatureHeader,
+		policy:          policy,
+	}, nil
+}
+
+// Send posts the week, signed with HMAC-SHA256 of the body when there's a secret
+func (notifier *jsonNotifier) Send(ctx context.Context, message any) (string, error) {
+	week, ok := message.(JsonWeek)
+	if !ok {
+		return "", fmt.Errorf("json can't send %T", message)
+	}
+	week.SentAt = time.Now().Format(time.RFC3339)
+
+	body, err := json.Marshal(week)
+	if err != nil {
+		return "", err
+	}
+
+	header := notifier.header.Clone()
+	if len(notifier.secret) > 0 {
+		signature := hmac.New(sha256.New, notifier.secret)
+		signature.Write(body)
+		header.Set(notifier.signatureHeader, "sha256="+hex.EncodeToString(signature.Sum(nil)))
+	}
+
+	_, err = notifier.policy.request(ctx, limitKey{"json", notifier.url}, http.MethodPost, notifier.url, body, header)
+	return "", err
+}
+
+func (notifier *jsonNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	return errors.ErrUnsupported
+}
+
+func (notifier *jsonNotifier) Delete(ctx context.Context, messageId string) error {
+	return errors.ErrUnsupported
+}
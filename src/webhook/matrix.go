@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type MatrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+type matrixEdit struct {
+	MatrixMessage
+	NewContent MatrixMessage `json:"m.new_content"`
+	RelatesTo  matrixRelates `json:"m.relates_to"`
+}
+
+type matrixRelates struct {
+	RelType string `json:"rel_type"`
+	EventId string `json:"event_id"`
+}
+
+type matrixEventResponse struct {
+	EventId string `json:"event_id"`
+}
+
+type matrixRoomResponse struct {
+	RoomId string `json:"room_id"`
+}
+
+type matrixNotifier struct {
+	homeserver string
+	room       string
+	header     http.Header
+	policy     Policy
+}
+
+func newMatrixNotifier(homeserver string, room string, token string, policy Policy) (*matrixNotifier, error) {
+	if homeserver == "" || room == "" || token == "" {
+		return nil, errors.New("matrix homeserver, room and token are required")
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+
+	return &matrixNotifier{
+		homeserver: strings.TrimSuffix(homeserver, "/"),
+		room:       room,
+		header:     header,
+		policy:     policy,
+	}, nil
+}
+
+// transactionId makes retries of the same request idempotent
+func transactionId() (string, error) {
+	buffer := make([]byte, 16)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buffer), nil
+}
+
+// roomId resolves a room alias such as #schedule:example.com once
+func (notifier *matrixNotifier) roomId(ctx context.Context) (string, error) {
+	if !strings.HasPrefix(notifier.room, "#") {
+		return notifier.room, nil
+	}
+
+	responseBody, err := notifier.policy.request(ctx, limitKey{"matrix", notifier.homeserver}, http.MethodGet, notifier.homeserver+"/_matrix/client/v3/directory/room/"+url.PathEscape(notifier.room), nil, notifier.header)
+	if err != nil {
+		return "", err
+	}
+
+	var response matrixRoomResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return "", err
+	}
+
+	notifier.room = response.RoomId
+	return notifier.room, nil
+}
+
+func (notifier *matrixNotifier) roomUrl(ctx context.Context, parts ...string) (string, error) {
+	roomId, err := notifier.roomId(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	txnId, err := transactionId()
+	if err != nil {
+		return "", err
+	}
+
+	escaped := []string{notifier.homeserver, "_matrix/client/v3/rooms", url.PathEscape(roomId)}
+	for _, part := range append(parts, txnId) {
+		escaped = append(escaped, url.PathEscape(part))
+	}
+	return strings.Join(escaped, "/"), nil
+}
+
+func (notifier *matrixNotifier) send(ctx context.Context, content any) (string, error) {
+	requestUrl, err := notifier.roomUrl(ctx, "send", "m.room.message")
+	if err != nil {
+		return "", err
+	}
+
+	responseBody, err := notifier.policy.request(ctx, limitKey{"matrix", notifier.homeserver}, http.MethodPut, requestUrl, content, notifier.header)
+	if err != nil {
+		return "", err
+	}
+
+	var response matrixEventResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return "", err
+	}
+
+	return response.EventId, nil
+}
+
+func (notifier *matrixNotifier) Send(ctx context.Context, message any) (string, error) {
+	matrixMessage, ok := message.(MatrixMessage)
+	if !ok {
+		return "", fmt.Errorf("matrix can't send %T", message)
+	}
+
+	return notifier.send(ctx, matrixMessage)
+}
+
+// Edit sends a replacement, the original event id keeps referring to the message
+func (notifier *matrixNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	matrixMessage, ok := message.(MatrixMessage)
+	if !ok {
+		return fmt.Errorf("matrix can't send %T", message)
+	}
+
+	// clients without edit support show the fallback
+	fallback := matrixMessage
+	fallback.Body = "* " + fallback.Body
+	if fallback.FormattedBody != "" {
+		fallback.FormattedBody = "* " + fallback.FormattedBody
+	}
+
+	_, err := notifier.send(ctx, matrixEdit{
+		MatrixMessage: fallback,
+		NewContent:    matrixMessage,
+		RelatesTo: matrixRelates{
+			RelType: "m.replace",
+			EventId: messageId,
+		},
+	})
+	return err
+}
+
+func (notifier *matrixNotifier) Delete(ctx context.Context, messageId string) error {
+	requestUrl, err := notifier.roomUrl(ctx, "redact", messageId)
+	if err != nil {
+		return err
+	}
+
+	_, err = notifier.policy.request(ctx, limitKey{"matrix", notifier.homeserver}, http.MethodPut, requestUrl, struct{}{}, notifier.header)
+	return err
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MattermostText is the longest post Mattermost accepts
+const MattermostText = 16383
+
+type MattermostMessage struct {
+	Text     string `json:"text"`
+	Username string `json:"username,omitempty"`
+	IconUrl  string `json:"icon_url,omitempty"`
+}
+
+type mattermostNotifier struct {
+	url    string
+	policy Policy
+}
+
+func newMattermostNotifier(webhookUrl string, policy Policy) (*mattermostNotifier, error) {
+	if webhookUrl == "" {
+		return nil, errors.New("mattermost webhook is required")
+	}
+
+	return &mattermostNotifier{
+		url:    webhookUrl,
+		policy: policy,
+	}, nil
+}
+
+// Send returns no id, Mattermost answers incoming webhooks with a plain "ok"
+func (notifier *mattermostNotifier) Send(ctx context.Context, message any) (string, error) {
+	mattermostMessage, ok := message.(MattermostMessage)
+	if !ok {
+		return "", fmt.Errorf("mattermost can't send %T", message)
+	}
+
+	_, err := notifier.policy.request(ctx, limitKey{"mattermost", notifier.url}, http.MethodPost, notifier.url, mattermostMessage, nil)
+	return "", err
+}
+
+func (notifier *mattermostNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	return errors.ErrUnsupported
+}
+
+func (notifier *mattermostNotifier) Delete(ctx context.Context, messageId string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+// Notifier posts messages rendered for its platform, such as Message for Discord or SlackMessage for Slack
+type Notifier interface {
+	// Send returns the id of the new message, which is empty if the platform can't refer to it later
+	Send(ctx context.Context, message any) (string, error)
+	// Edit returns errors.ErrUnsupported when messages can't be edited
+	Edit(ctx context.Context, messageId string, message any) error
+	// Delete returns errors.ErrUnsupported when messages can't be deleted
+	Delete(ctx context.Context, messageId string) error
+}
+
+func New(conf config.Destination) (Notifier, error) {
+	policy, err := NewPolicy(conf.Retry)
+	if err != nil {
+		return nil, err
+	}
+
+	switch conf.Type {
+	case "", "discord":
+		return newDiscordNotifier(conf.Webhook, policy)
+	case "slack":
+		return newSlackNotifier(conf.Webhook, policy)
+	case "matrix":
+		return newMatrixNotifier(conf.Homeserver, conf.Room, conf.Token, policy)
+	case "telegram":
+		return newTelegramNotifier(conf.Api, conf.Token, conf.Chat, conf.Pin, policy)
+	case "email":
+		return newEmailNotifier(conf.Smtp, conf.Recipients, policy)
+	case "json":
+		return newJsonNotifier(conf.Webhook, conf.Headers, conf.Secret, conf.SignatureHeader, policy)
+	case "teams":
+		return newTeamsNotifier(conf.Webhook, policy)
+	case "mattermost":
+		return newMattermostNotifier(conf.Webhook, policy)
+	default:
+		return nil, fmt.Errorf("unknown destination type %s", conf.Type)
+	}
+}
+
+// Id tells destinations apart, it's the webhook for the ones that have it
+func Id(conf config.Destination) string {
+	switch conf.Type {
+	case "matrix":
+		return conf.Type + ":" + conf.Homeserver + "/" + conf.Room
+	case "telegram":
+		return conf.Type + ":" + conf.Chat
+	case "email":
+		return conf.Type + ":" + strings.Join(conf.Recipients, ",")
+	default:
+		return conf.Webhook
+	}
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
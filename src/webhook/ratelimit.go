@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// bucket is the rate limit of one destination, its lock lets a single request through at a time
+type bucket struct {
+	lock      chan struct{}
+	remaining int
+	resetAt   time.Time
+}
+
+// limitKey names the bucket of a destination, global limits only hold up the buckets of their own platform
+type limitKey struct {
+	platform string
+	bucket   string
+}
+
+// limiter is shared by every notifier, so calendars posting to the same destination wait for each other
+type limiter struct {
+	mutex   sync.Mutex
+	buckets map[limitKey]*bucket
+	global  map[string]time.Time
+}
+
+var limits = &limiter{
+	buckets: make(map[limitKey]*bucket),
+	global:  make(map[string]time.Time),
+}
+
+func (limiter *limiter) bucket(key limitKey) *bucket {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	found, ok := limiter.buckets[key]
+	if !ok {
+		found = &bucket{
+			lock:      make(chan struct{}, 1),
+			remaining: -1,
+		}
+		limiter.buckets[key] = found
+	}
+	return found
+}
+
+func (limiter *limiter) globalReset(platform string) time.Time {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return limiter.global[platform]
+}
+
+// acquire waits for the bucket to be free and to have requests left, the returned function releases it
+func (limiter *limiter) acquire(ctx context.Context, key limitKey) (*bucket, func(), error) {
+	found := limiter.bucket(key)
+
+	select {
+	case found.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	release := func() {
+		<-found.lock
+	}
+
+	waitUntil := limiter.globalReset(key.platform)
+	if found.remaining == 0 && found.resetAt.After(waitUntil) {
+		waitUntil = found.resetAt
+	}
+
+	if wait := time.Until(waitUntil); wait > 0 {
+		log.Debug().
+			Dur("wait", wait).
+			Msg("Waiting for the rate limit to reset")
+		if err := sleep(ctx, wait); err != nil {
+			release()
+			return nil, nil, err
+		}
+	}
+
+	return found, release, nil
+}
+
+type globalResponse struct {
+	Global bool `json:"global"`
+}
+
+// update remembers what the response said about the limits, called while the bucket is still held
+func (limiter *limiter) update(key limitKey, found *bucket, resp *http.Response, body []byte) {
+	now := time.Now()
+
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		found.remaining = remaining
+		if after := resetAfter(resp.Header); after > 0 {
+			found.resetAt = now.Add(after)
+		}
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+
+	after := retryAfter(resp.Header, body)
+	response := globalResponse{}
+	json.Unmarshal(body, &response)
+
+	if response.Global || resp.Header.Get("X-RateLimit-Global") == "true" || resp.Header.Get("X-RateLimit-Scope") == "global" {
+		limiter.mutex.Lock()
+		if reset := now.Add(after); reset.After(limiter.global[key.platform]) {
+			limiter.global[key.platform] = reset
+		}
+		limiter.mutex.Unlock()
+
+		log.Warn().
+			Str("platform", key.platform).
+			Dur("wait", after).
+			Msg("Hit the global rate limit")
+		return
+	}
+
+	found.remaining = 0
+	if reset := now.Add(after); reset.After(found.resetAt) {
+		found.resetAt = reset
+	}
+}
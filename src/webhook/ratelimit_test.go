@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterWaitsForReset(t *testing.T) {
+	const limit = 2
+	const window = 300 * time.Millisecond
+
+	var mutex sync.Mutex
+	remaining := limit
+	reset := time.Now().Add(window)
+	var limited atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if time.Now().After(reset) {
+			remaining = limit
+			reset = time.Now().Add(window)
+		}
+		if remaining == 0 {
+			limited.Add(1)
+			writer.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(writer, `{"retry_after":%.3f}`, time.Until(reset).Seconds())
+			return
+		}
+
+		remaining--
+		writer.Header().Set("X-RateLimit-Remaining", fmt.Sprint(remaining))
+		writer.Header().Set("X-RateLimit-Reset-After", fmt.Sprintf("%.3f", time.Until(reset).Seconds()))
+		fmt.Fprint(writer, `{"id":"1"}`)
+	}))
+	defer server.Close()
+
+	// every thread of the webhook shares its limit
+	var wait sync.WaitGroup
+	for thread := 0; thread < 6; thread++ {
+		wait.Add(1)
+		go func(thread int) {
+			defer wait.Done()
+			notifier := &discordNotifier{url: fmt.Sprintf("%s/webhook?thread_id=%d", server.URL, thread), policy: testPolicy}
+			if _, err := notifier.Send(context.Background(), Message{Content: "day"}); err != nil {
+				t.Error(err)
+			}
+		}(thread)
+	}
+	wait.Wait()
+
+	if count := limited.Load(); count != 0 {
+		t.Errorf("hit the rate limit %d times", count)
+	}
+}
+
+func TestLimiterGlobalPerPlatform(t *testing.T) {
+	var globalHit atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/discord" && globalHit.CompareAndSwap(false, true) {
+			writer.Header().Set("X-RateLimit-Global", "true")
+			writer.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(writer, `{"message":"You are being rate limited.","retry_after":1,"global":true}`)
+			return
+		}
+		fmt.Fprint(writer, `{"id":"1"}`)
+	}))
+	defer server.Close()
+
+	discord := &discordNotifier{url: server.URL + "/discord", policy: Policy{Attempts: 1}}
+	if _, err := discord.Send(context.Background(), Message{Content: "day"}); err == nil {
+		t.Fatal("globally rate limited send succeeded")
+	}
+
+	// other platforms don't wait for Discord
+	start := time.Now()
+	slack := &slackNotifier{url: server.URL + "/slack", policy: testPolicy}
+	if _, err := slack.Send(context.Background(), SlackMessage{Text: "day"}); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > 500*time.Millisecond {
+		t.Errorf("slack waited %s for the Discord global limit", waited)
+	}
+
+	// but other Discord webhooks do
+	start = time.Now()
+	other := &discordNotifier{url: server.URL + "/other", policy: testPolicy}
+	if _, err := other.Send(context.Background(), Message{Content: "day"}); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 500*time.Millisecond {
+		t.Errorf("discord waited only %s for its global limit", waited)
+	}
+}
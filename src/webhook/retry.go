@@ -0,0 +1,346 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/aleksasiriski/smerac-go/src/config"
+)
+
+var (
+	ErrNotFound       = errors.New("message or webhook not found")
+	ErrUnknownWebhook = errors.New("unknown webhook")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrRateLimited    = errors.New("rate limited")
+)
+
+// Discord error codes for a webhook that no longer exists
+const (
+	discordUnknownWebhook      = 10015
+	discordInvalidWebhookToken = 50027
+)
+
+// Policy decides how often and how long failed requests are retried
+type Policy struct {
+	Attempts   int
+	Deadline   time.Duration
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultPolicy = Policy{
+	Attempts:   5,
+	Deadline:   2 * time.Minute,
+	MinBackoff: time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+func NewPolicy(conf config.Retry) (Policy, error) {
+	policy := DefaultPolicy
+	if conf.Attempts > 0 {
+		policy.Attempts = conf.Attempts
+	}
+
+	for _, duration := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"deadline", conf.Deadline, &policy.Deadline},
+		{"min_backoff", conf.MinBackoff, &policy.MinBackoff},
+		{"max_backoff", conf.MaxBackoff, &policy.MaxBackoff},
+	} {
+		if duration.value == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return policy, fmt.Errorf("invalid retry %s %s: %w", duration.name, duration.value, err)
+		}
+		*duration.field = parsed
+	}
+
+	if policy.MaxBackoff < policy.MinBackoff {
+		policy.MaxBackoff = policy.MinBackoff
+	}
+
+	return policy, nil
+}
+
+// StatusError is a response that wasn't a success, it unwraps to the matching typed error
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+	// RetryAfter is how long the server asked to wait, zero when it didn't say
+	RetryAfter time.Duration
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("HTTP request failed with status %d, body: \n %s", err.StatusCode, err.Body)
+}
+
+type discordError struct {
+	Code int `json:"code"`
+}
+
+func (err *StatusError) Unwrap() error {
+	response := discordError{}
+	json.Unmarshal(err.Body, &response)
+
+	switch {
+	case response.Code == discordUnknownWebhook || response.Code == discordInvalidWebhookToken:
+		return ErrUnknownWebhook
+	case err.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case err.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return nil
+	}
+}
+
+// Temporary tells if the same request could succeed later
+func (err *StatusError) Temporary() bool {
+	switch err.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+type retryResponse struct {
+	RetryAfter   float64 `json:"retry_after"`
+	RetryAfterMs int64   `json:"retry_after_ms"`
+	Parameters   struct {
+		RetryAfter float64 `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// retryAfter reads how long a rate limited request has to wait, from the headers or the body, zero when it isn't said
+func retryAfter(header http.Header, body []byte) time.Duration {
+	if after := resetAfter(header); after > 0 {
+		return after
+	}
+
+	response := retryResponse{}
+	if err := json.Unmarshal(body, &response); err == nil {
+		switch {
+		case response.RetryAfterMs > 0:
+			return time.Duration(response.RetryAfterMs) * time.Millisecond
+		case response.RetryAfter > 0:
+			return fromSeconds(response.RetryAfter)
+		case response.Parameters.RetryAfter > 0:
+			return fromSeconds(response.Parameters.RetryAfter)
+		}
+	}
+
+	return 0
+}
+
+// resetAfter reads how long until the limit resets from the headers alone
+func resetAfter(header http.Header) time.Duration {
+	for _, name := range []string{"X-RateLimit-Reset-After", "Retry-After"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return fromSeconds(seconds)
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return time.Until(date)
+		}
+	}
+
+	// the moment the bucket resets, in seconds since the epoch
+	if value := header.Get("X-RateLimit-Reset"); value != "" {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			return time.Until(time.Unix(0, 0).Add(fromSeconds(seconds)))
+		}
+	}
+
+	return 0
+}
+
+func fromSeconds(seconds float64) time.Duration {
+	whole, frac := math.Modf(seconds)
+	return time.Duration(whole)*time.Second + time.Duration(frac*1000)*time.Millisecond
+}
+
+// backoff grows exponentially with every attempt, with half of it random so retries don't line up
+func (policy Policy) backoff(attempt int) time.Duration {
+	backoff := policy.MaxBackoff
+	if attempt < 32 {
+		if exponential := policy.MinBackoff << attempt; exponential > 0 && exponential < backoff {
+			backoff = exponential
+		}
+	}
+
+	half := backoff / 2
+	if half <= 0 {
+		return backoff
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// wait returns how long to wait before retrying the error, or false when it can't be retried
+func (policy Policy) wait(ctx context.Context, err error, attempt int) (time.Duration, bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
+
+	var statusErr *StatusError
+	var smtpErr *textproto.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &statusErr):
+		if !statusErr.Temporary() {
+			return 0, false
+		}
+		if statusErr.RetryAfter > 0 {
+			// a little extra, so the limit has surely reset
+			return statusErr.RetryAfter + 250*time.Millisecond, true
+		}
+	case errors.As(err, &smtpErr):
+		// 4xx replies are the transient ones
+		if smtpErr.Code < 400 || smtpErr.Code >= 500 {
+			return 0, false
+		}
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF):
+	default:
+		return 0, false
+	}
+
+	return policy.backoff(attempt), true
+}
+
+// retry runs the attempt until it succeeds, fails for good, runs out of attempts or would pass the deadline
+func (policy Policy) retry(ctx context.Context, try func(ctx context.Context) error) error {
+	if policy.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Deadline)
+		defer cancel()
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := try(ctx)
+		if err == nil {
+			return nil
+		}
+
+		wait, retryable := policy.wait(ctx, err, attempt)
+		if !retryable {
+			return err
+		}
+		if attempt+1 >= policy.Attempts {
+			return fmt.Errorf("gave up after %d attempts: %w", attempt+1, err)
+		}
+		if deadline, found := ctx.Deadline(); found && time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("retrying in %s would pass the deadline: %w", wait, err)
+		}
+
+		log.Debug().
+			Err(err).
+			Int("attempt", attempt+1).
+			Dur("wait", wait).
+			Msg("Retrying request")
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// redact drops the URL from request errors, webhook and bot URLs hold their tokens
+func redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// request sends the payload as JSON, retrying it as the policy allows,
+// requests with the same key share a rate limit and are sent one at a time
+func (policy Policy) request(ctx context.Context, key limitKey, method string, requestUrl string, payload any, header http.Header) ([]byte, error) {
+	var responseBody []byte
+
+	err := policy.retry(ctx, func(ctx context.Context) error {
+		body := new(bytes.Buffer)
+
+		// payloads that are already encoded are sent as they are, so they can be signed
+		switch payload := payload.(type) {
+		case nil:
+		case []byte:
+			body.Write(payload)
+		default:
+			if err := json.NewEncoder(body).Encode(payload); err != nil {
+				return err
+			}
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequestWithContext(ctx, method, requestUrl, body)
+		if err != nil {
+			return redact(err)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		bucket, release, err := limits.acquire(ctx, key)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return redact(err)
+		}
+
+		content, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		limits.update(key, bucket, resp, content)
+
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent:
+			// Success
+			responseBody = content
+			return nil
+		default:
+			return &StatusError{
+				StatusCode: resp.StatusCode,
+				Body:       content,
+				RetryAfter: retryAfter(resp.Header, content),
+			}
+		}
+	})
+
+	return responseBody, err
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Slack Block Kit limits
+const (
+	SlackHeaderText  = 150
+	SlackSectionText = 3000
+	SlackBlocks      = 50
+)
+
+var slackEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// SlackEscape escapes the characters Slack's mrkdwn uses for links and mentions
+func SlackEscape(text string) string {
+	return slackEscaper.Replace(text)
+}
+
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks,omitempty"`
+}
+
+type SlackBlock struct {
+	Type string     `json:"type"`
+	Text *SlackText `json:"text,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackNotifier struct {
+	url    string
+	policy Policy
+}
+
+func newSlackNotifier(webhookUrl string, policy Policy) (*slackNotifier, error) {
+	if webhookUrl == "" {
+		return nil, errors.New("slack webhook is required")
+	}
+
+	return &slackNotifier{
+		url:    webhookUrl,
+		policy: policy,
+	}, nil
+}
+
+// Send returns no id, Slack answers incoming webhooks with a plain "ok" instead of the message timestamp
+func (notifier *slackNotifier) Send(ctx context.Context, message any) (string, error) {
+	slackMessage, ok := message.(SlackMessage)
+	if !ok {
+		return "", fmt.Errorf("slack can't send %T", message)
+	}
+
+	_, err := notifier.policy.request(ctx, limitKey{"slack", notifier.url}, http.MethodPost, notifier.url, slackMessage, nil)
+	return "", err
+}
+
+func (notifier *slackNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	return errors.ErrUnsupported
+}
+
+func (notifier *slackNotifier) Delete(ctx context.Context, messageId string) error {
+	return errors.ErrUnsupported
+}
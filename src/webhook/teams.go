@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type TeamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     AdaptiveCard `json:"content"`
+}
+
+type AdaptiveCard struct {
+	Schema  string          `json:"$schema"`
+	Type    string          `json:"type"`
+	Version string          `json:"version"`
+	Body    []AdaptiveBlock `json:"body"`
+}
+
+type AdaptiveBlock struct {
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	Size      string `json:"size,omitempty"`
+	Weight    string `json:"weight,omitempty"`
+	Wrap      bool   `json:"wrap,omitempty"`
+	Spacing   string `json:"spacing,omitempty"`
+	Separator bool   `json:"separator,omitempty"`
+}
+
+// NewTeamsMessage wraps the blocks into an Adaptive Card the way Workflows and incoming webhooks expect
+func NewTeamsMessage(blocks []AdaptiveBlock) TeamsMessage {
+	return TeamsMessage{
+		Type: "message",
+		Attachments: []TeamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: AdaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    blocks,
+			},
+		}},
+	}
+}
+
+type teamsNotifier struct {
+	url    string
+	policy Policy
+}
+
+func newTeamsNotifier(webhookUrl string, policy Policy) (*teamsNotifier, error) {
+	if webhookUrl == "" {
+		return nil, errors.New("teams webhook is required")
+	}
+
+	return &teamsNotifier{
+		url:    webhookUrl,
+		policy: policy,
+	}, nil
+}
+
+// Send returns no id, Teams accepts the card without saying where it was posted
+func (notifier *teamsNotifier) Send(ctx context.Context, message any) (string, error) {
+	teamsMessage, ok := message.(TeamsMessage)
+	if !ok {
+		return "", fmt.Errorf("teams can't send %T", message)
+	}
+
+	_, err := notifier.policy.request(ctx, limitKey{"teams", notifier.url}, http.MethodPost, notifier.url, teamsMessage, nil)
+	return "", err
+}
+
+func (notifier *teamsNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	return errors.ErrUnsupported
+}
+
+func (notifier *teamsNotifier) Delete(ctx context.Context, messageId string) error {
+	return errors.ErrUnsupported
+}
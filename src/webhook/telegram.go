@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const defaultTelegramApi = "https://api.telegram.org"
+
+// TelegramText is the longest message text Telegram accepts
+const TelegramText = 4096
+
+type TelegramMessage struct {
+	Text string `json:"text"`
+	// Pin marks the message that is pinned after it's posted, when the destination pins
+	Pin bool `json:"-"`
+}
+
+type telegramRequest struct {
+	ChatId              string               `json:"chat_id"`
+	MessageId           int64                `json:"message_id,omitempty"`
+	Text                string               `json:"text,omitempty"`
+	ParseMode           string               `json:"parse_mode,omitempty"`
+	DisableNotification bool                 `json:"disable_notification,omitempty"`
+	LinkPreviewOptions  *telegramLinkPreview `json:"link_preview_options,omitempty"`
+}
+
+type telegramLinkPreview struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+type telegramResponse struct {
+	Ok          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      struct {
+		MessageId int64 `json:"message_id"`
+	} `json:"result"`
+}
+
+type telegramNotifier struct {
+	api    string
+	chat   string
+	pin    bool
+	policy Policy
+}
+
+func newTelegramNotifier(api string, token string, chat string, pin bool, policy Policy) (*telegramNotifier, error) {
+	if token == "" || chat == "" {
+		return nil, errors.New("telegram token and chat are required")
+	}
+	if api == "" {
+		api = defaultTelegramApi
+	}
+
+	return &telegramNotifier{
+		api:    strings.TrimSuffix(api, "/") + "/bot" + token,
+		chat:   chat,
+		pin:    pin,
+		policy: policy,
+	}, nil
+}
+
+// call runs a Bot API method, turning the errors about missing messages into ErrNotFound
+func (notifier *telegramNotifier) call(ctx context.Context, method string, payload telegramRequest) (telegramResponse, error) {
+	payload.ChatId = notifier.chat
+
+	response := telegramResponse{}
+	responseBody, err := notifier.policy.request(ctx, limitKey{"telegram", notifier.api + "#" + notifier.chat}, http.MethodPost, notifier.api+"/"+method, payload, nil)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadRequest {
+			switch {
+			case bytes.Contains(statusErr.Body, []byte("message is not modified")):
+				return response, nil
+			case bytes.Contains(statusErr.Body, []byte("message to edit not found")),
+				bytes.Contains(statusErr.Body, []byte("message to delete not found")),
+				bytes.Contains(statusErr.Body, []byte("message can't be deleted")):
+				return response, fmt.Errorf("%w: %w", ErrNotFound, err)
+			}
+		}
+		return response, err
+	}
+
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return response, err
+	}
+	if !response.Ok {
+		return response, fmt.Errorf("telegram %s failed: %s", method, response.Description)
+	}
+
+	return response, nil
+}
+
+func (notifier *telegramNotifier) message(message any) (telegramRequest, bool, error) {
+	telegramMessage, ok := message.(TelegramMessage)
+	if !ok {
+		return telegramRequest{}, false, fmt.Errorf("telegram can't send %T", message)
+	}
+
+	payload := telegramRequest{
+		Text:      telegramMessage.Text,
+		ParseMode: "HTML",
+		LinkPreviewOptions: &telegramLinkPreview{
+			IsDisabled: true,
+		},
+	}
+	return payload, telegramMessage.Pin && notifier.pin, nil
+}
+
+func (notifier *telegramNotifier) Send(ctx context.Context, message any) (string, error) {
+	payload, pin, err := notifier.message(message)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := notifier.call(ctx, "sendMessage", payload)
+	if err != nil {
+		return "", err
+	}
+	messageId := response.Result.MessageId
+
+	// the message is already posted, so a failed pin mustn't lose track of it
+	if pin {
+		if _, err := notifier.call(ctx, "pinChatMessage", telegramRequest{
+			MessageId:           messageId,
+			DisableNotification: true,
+		}); err != nil {
+			log.Warn().
+				Err(err).
+				Int64("message", messageId).
+				Msg("Failed pinning message")
+		}
+	}
+
+	return strconv.FormatInt(messageId, 10), nil
+}
+
+func (notifier *telegramNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	payload, _, err := notifier.message(message)
+	if err != nil {
+		return err
+	}
+
+	if payload.MessageId, err = strconv.ParseInt(messageId, 10, 64); err != nil {
+		return fmt.Errorf("%w: invalid message id %s", ErrNotFound, messageId)
+	}
+
+	_, err = notifier.call(ctx, "editMessageText", payload)
+	return err
+}
+
+func (notifier *telegramNotifier) Delete(ctx context.Context, messageId string) error {
+	id, err := strconv.ParseInt(messageId, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid message id %s", ErrNotFound, messageId)
+	}
+
+	_, err = notifier.call(ctx, "deleteMessage", telegramRequest{
+		MessageId: id,
+	})
+	return err
+}
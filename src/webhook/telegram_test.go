@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	Attempts:   3,
+	Deadline:   10 * time.Second,
+	MinBackoff: 10 * time.Millisecond,
+	MaxBackoff: 10 * time.Millisecond,
+}
+
+type telegramCall struct {
+	method  string
+	request telegramRequest
+}
+
+// telegramServer is a Bot API stand-in, respond answers every call after it's recorded
+type telegramServer struct {
+	t       *testing.T
+	server  *httptest.Server
+	mutex   sync.Mutex
+	calls   []telegramCall
+	respond func(method string, writer http.ResponseWriter)
+}
+
+func newTelegramServer(t *testing.T, respond func(method string, writer http.ResponseWriter)) *telegramServer {
+	t.Helper()
+
+	stub := &telegramServer{
+		t:       t,
+		respond: respond,
+	}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		method, found := strings.CutPrefix(request.URL.Path, "/bottoken/")
+		if !found {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		payload := telegramRequest{}
+		if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+
+		stub.mutex.Lock()
+		stub.calls = append(stub.calls, telegramCall{method, payload})
+		stub.mutex.Unlock()
+
+		stub.respond(method, writer)
+	}))
+	t.Cleanup(stub.server.Close)
+
+	return stub
+}
+
+func (stub *telegramServer) notifier(pin bool) *telegramNotifier {
+	stub.t.Helper()
+
+	notifier, err := newTelegramNotifier(stub.server.URL, "token", "-100", pin, testPolicy)
+	if err != nil {
+		stub.t.Fatal(err)
+	}
+	return notifier
+}
+
+func (stub *telegramServer) methods() []string {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+
+	methods := make([]string, 0, len(stub.calls))
+	for _, call := range stub.calls {
+		methods = append(methods, call.method)
+	}
+	return methods
+}
+
+func telegramOk(writer http.ResponseWriter, messageId int64) {
+	fmt.Fprintf(writer, `{"ok":true,"result":{"message_id":%d}}`, messageId)
+}
+
+func TestTelegramNotModified(t *testing.T) {
+	stub := newTelegramServer(t, func(method string, writer http.ResponseWriter) {
+		writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(writer, `{"ok":false,"error_code":400,"description":"Bad Request: message is not modified: specified new message content and reply markup are exactly the same"}`)
+	})
+
+	if err := stub.notifier(false).Edit(context.Background(), "42", TelegramMessage{Text: "same"}); err != nil {
+		t.Errorf("unchanged edit failed: %v", err)
+	}
+	if methods := stub.methods(); len(methods) != 1 || methods[0] != "editMessageText" {
+		t.Errorf("calls = %v, want a single editMessageText", methods)
+	}
+}
+
+func TestTelegramNotFound(t *testing.T) {
+	stub := newTelegramServer(t, func(method string, writer http.ResponseWriter) {
+		writer.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(writer, `{"ok":false,"error_code":400,"description":"Bad Request: message to edit not found"}`)
+	})
+
+	err := stub.notifier(false).Edit(context.Background(), "42", TelegramMessage{Text: "gone"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTelegramRetryAfter(t *testing.T) {
+	var mutex sync.Mutex
+	limited := time.Time{}
+	stub := newTelegramServer(t, func(method string, writer http.ResponseWriter) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if limited.IsZero() {
+			limited = time.Now()
+			writer.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(writer, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`)
+			return
+		}
+		if waited := time.Since(limited); waited < time.Second {
+			t.Errorf("retried after %s, before retry_after passed", waited)
+		}
+		telegramOk(writer, 7)
+	})
+
+	messageId, err := stub.notifier(false).Send(context.Background(), TelegramMessage{Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageId != "7" {
+		t.Errorf("message id = %s, want 7", messageId)
+	}
+	if methods := stub.methods(); len(methods) != 2 {
+		t.Errorf("calls = %v, want the send and its retry", methods)
+	}
+}
+
+func TestTelegramPin(t *testing.T) {
+	tests := []struct {
+		name    string
+		pin     bool
+		message bool
+		methods []string
+	}{
+		{"pinned", true, true, []string{"sendMessage", "pinChatMessage"}},
+		{"destination doesn't pin", false, true, []string{"sendMessage"}},
+		{"message isn't pinned", true, false, []string{"sendMessage"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newTelegramServer(t, func(method string, writer http.ResponseWriter) {
+				telegramOk(writer, 9)
+			})
+
+			if _, err := stub.notifier(test.pin).Send(context.Background(), TelegramMessage{Text: "week", Pin: test.message}); err != nil {
+				t.Fatal(err)
+			}
+
+			methods := stub.methods()
+			if strings.Join(methods, ",") != strings.Join(test.methods, ",") {
+				t.Fatalf("calls = %v, want %v", methods, test.methods)
+			}
+			if test.methods[len(test.methods)-1] == "pinChatMessage" {
+				pin := stub.calls[1].request
+				if pin.MessageId != 9 || pin.ChatId != "-100" || !pin.DisableNotification {
+					t.Errorf("unexpected pin request %+v", pin)
+				}
+			}
+		})
+	}
+}
+
+func TestTelegramPinFailure(t *testing.T) {
+	stub := newTelegramServer(t, func(method string, writer http.ResponseWriter) {
+		if method == "pinChatMessage" {
+			writer.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(writer, `{"ok":false,"error_code":400,"description":"Bad Request: not enough rights to pin a message"}`)
+			return
+		}
+		telegramOk(writer, 11)
+	})
+
+	// the message is posted, so its id is still returned
+	messageId, err := stub.notifier(true).Send(context.Background(), TelegramMessage{Text: "week", Pin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageId != "11" {
+		t.Errorf("message id = %s, want 11", messageId)
+	}
+}
+
+func TestTelegramRedactsToken(t *testing.T) {
+	stub := newTelegramServer(t, func(method string, writer http.ResponseWriter) {
+		telegramOk(writer, 1)
+	})
+	notifier := stub.notifier(false)
+	// nothing listens anymore, so the request fails before it gets a response
+	stub.server.Close()
+
+	_, err := notifier.Send(context.Background(), TelegramMessage{Text: "hello"})
+	if err == nil {
+		t.Fatal("send to a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), "bottoken") {
+		t.Errorf("error leaks the bot token: %v", err)
+	}
+}
@@ -12,15 +12,16 @@ type Message struct {
 }
 
 type Embed struct {
-	Title       string    `json:"title,omitempty"`
-	Url         string    `json:"url,omitempty"`
-	Description string    `json:"description,omitempty"`
-	Color       string    `json:"color,omitempty"`
-	Author      Author    `json:"author,omitempty"`
-	Fields      []Field   `json:"fields,omitempty"`
-	Thumbnail   Thumbnail `json:"thumbnail,omitempty"`
-	Image       Image     `json:"image,omitempty"`
-	Footer      Footer    `json:"footer,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Url         string     `json:"url,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Color       int        `json:"color,omitempty"`
+	Author      *Author    `json:"author,omitempty"`
+	Fields      []Field    `json:"fields,omitempty"`
+	Thumbnail   *Thumbnail `json:"thumbnail,omitempty"`
+	Image       *Image     `json:"image,omitempty"`
+	Footer      *Footer    `json:"footer,omitempty"`
+	Timestamp   string     `json:"timestamp,omitempty"`
 }
 
 type Author struct {
@@ -53,3 +54,18 @@ type AllowedMentions struct {
 	Users []string `json:"users,omitempty"`
 	Roles []string `json:"roles,omitempty"`
 }
+
+type messageResponse struct {
+	Id string `json:"id"`
+}
+
+type Limits struct {
+	Content          int
+	EmbedTitle       int
+	EmbedDescription int
+	FieldName        int
+	FieldValue       int
+	Fields           int
+	EmbedTotal       int
+	Embeds           int
+}
@@ -1,74 +1,142 @@
 package webhook
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log"
-	"math"
 	"net/http"
-	"strconv"
-	"time"
+	"net/url"
+	"strings"
 )
 
-func SendMessage(url string, message Message) error {
-	return SendMessageToDiscord(url, message)
+var DiscordLimits = Limits{
+	Content:          2000,
+	EmbedTitle:       256,
+	EmbedDescription: 4096,
+	FieldName:        256,
+	FieldValue:       1024,
+	Fields:           25,
+	EmbedTotal:       6000,
+	Embeds:           10,
 }
 
-func SendMessageToDiscord(url string, message Message) error {
+func SendMessage(ctx context.Context, url string, message Message) (string, error) {
+	return SendMessageToDiscord(ctx, url, message)
+}
+
+func SendMessageToDiscord(ctx context.Context, webhookUrl string, message Message) (string, error) {
+	return (&discordNotifier{url: webhookUrl, policy: DefaultPolicy}).send(ctx, message)
+}
+
+func EditMessageOnDiscord(ctx context.Context, webhookUrl string, messageId string, message Message) error {
+	return (&discordNotifier{url: webhookUrl, policy: DefaultPolicy}).edit(ctx, messageId, message)
+}
+
+func DeleteMessageFromDiscord(ctx context.Context, webhookUrl string, messageId string) error {
+	return (&discordNotifier{url: webhookUrl, policy: DefaultPolicy}).Delete(ctx, messageId)
+}
+
+// discordUrl keeps any query the webhook was configured with, such as thread_id
+func discordUrl(webhookUrl string, messageId string, wait bool) (string, error) {
 	// Validate parameters
-	if url == "" {
-		return errors.New("empty URL")
+	if webhookUrl == "" {
+		return "", errors.New("empty URL")
+	}
+
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if messageId != "" {
+		parsedUrl = parsedUrl.JoinPath("messages", messageId)
 	}
 
-	for {
-		payload := new(bytes.Buffer)
-
-		err := json.NewEncoder(payload).Encode(message)
-		if err != nil {
-			return err
-		}
-
-		// Make the HTTP request
-		resp, err := http.Post(url, "application/json", payload)
-
-		if err != nil {
-			return err
-		}
-		if err != nil {
-			log.Printf("HTTP request failed: %v", err)
-			return err
-		}
-
-		switch resp.StatusCode {
-		case http.StatusOK, http.StatusNoContent:
-			// Success
-			resp.Body.Close()
-			return nil
-		case http.StatusTooManyRequests:
-			// Rate limit exceeded, retry after backoff duration
-			resetAfter := resp.Header.Get("X-RateLimit-Reset-After")
-			parsedAfter, err := strconv.ParseFloat(resetAfter, 64)
-			if err != nil {
-				return err
-			}
-
-			whole, frac := math.Modf(parsedAfter)
-			resetAt := time.Now().Add(time.Duration(whole) * time.Second).Add(time.Duration(frac*1000) * time.Millisecond).Add(250 * time.Millisecond)
-
-			time.Sleep(time.Until(resetAt))
-
-		default:
-			// Handle other HTTP status codes
-			defer resp.Body.Close()
-			responseBody, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return err
-			}
-
-			return fmt.Errorf("HTTP request failed with status %d, body: \n %s", resp.StatusCode, responseBody)
-		}
+	if wait {
+		query := parsedUrl.Query()
+		query.Set("wait", "true")
+		parsedUrl.RawQuery = query.Encode()
 	}
+
+	return parsedUrl.String(), nil
+}
+
+type discordNotifier struct {
+	url    string
+	policy Policy
+}
+
+func newDiscordNotifier(webhookUrl string, policy Policy) (*discordNotifier, error) {
+	if webhookUrl == "" {
+		return nil, errors.New("discord webhook is required")
+	}
+
+	return &discordNotifier{
+		url:    webhookUrl,
+		policy: policy,
+	}, nil
+}
+
+// bucket is the webhook without its query, threads of one webhook share its rate limit
+func (notifier *discordNotifier) bucket() string {
+	bucket, _, _ := strings.Cut(notifier.url, "?")
+	return bucket
+}
+
+func (notifier *discordNotifier) send(ctx context.Context, message Message) (string, error) {
+	requestUrl, err := discordUrl(notifier.url, "", true)
+	if err != nil {
+		return "", err
+	}
+
+	responseBody, err := notifier.policy.request(ctx, limitKey{"discord", notifier.bucket()}, http.MethodPost, requestUrl, message, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var response messageResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return "", err
+	}
+
+	return response.Id, nil
+}
+
+func (notifier *discordNotifier) edit(ctx context.Context, messageId string, message Message) error {
+	requestUrl, err := discordUrl(notifier.url, messageId, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = notifier.policy.request(ctx, limitKey{"discord", notifier.bucket()}, http.MethodPatch, requestUrl, message, nil)
+	return err
+}
+
+func (notifier *discordNotifier) Send(ctx context.Context, message any) (string, error) {
+	discordMessage, ok := message.(Message)
+	if !ok {
+		return "", fmt.Errorf("discord can't send %T", message)
+	}
+
+	return notifier.send(ctx, discordMessage)
+}
+
+func (notifier *discordNotifier) Edit(ctx context.Context, messageId string, message any) error {
+	discordMessage, ok := message.(Message)
+	if !ok {
+		return fmt.Errorf("discord can't send %T", message)
+	}
+
+	return notifier.edit(ctx, messageId, discordMessage)
+}
+
+func (notifier *discordNotifier) Delete(ctx context.Context, messageId string) error {
+	requestUrl, err := discordUrl(notifier.url, messageId, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = notifier.policy.request(ctx, limitKey{"discord", notifier.bucket()}, http.MethodDelete, requestUrl, nil, nil)
+	return err
 }